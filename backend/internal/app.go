@@ -8,6 +8,7 @@ import (
 	"os"
 	"videocall/internal/domain/repositories"
 	"videocall/internal/infrastructure/auth"
+	"videocall/internal/infrastructure/bus"
 	"videocall/internal/infrastructure/config"
 	"videocall/internal/infrastructure/database"
 	"videocall/internal/infrastructure/push"
@@ -27,9 +28,13 @@ func Run(ctx context.Context, cfg *config.Config) error {
 	roomRepo := storageFactory.CreateRoomRepository(ctx)
 	userRepo := storageFactory.CreateUserRepository()
 	tokenRepo := storageFactory.CreateRefreshTokenRepository(ctx)
+	ticketRepo := storageFactory.CreateSignalTicketRepository(ctx)
+	chatRepo := storageFactory.CreateChatRepository()
+	callRepo := storageFactory.CreateCallRepository()
 
 	jwt := auth.NewJWT(cfg)
 	refreshTokenService := token.NewRefreshTokenService(tokenRepo, cfg.RefreshToken.TTL)
+	signalTickets := token.NewSignalTicketService(ticketRepo, cfg.JWT.Secret, cfg.Signaling.TicketTTL)
 
 	var pushService *push.Service
 	if cfg.VAPID.PublicKey != "" && cfg.VAPID.PrivateKey != "" {
@@ -39,11 +44,17 @@ func Run(ctx context.Context, cfg *config.Config) error {
 		log.Println("⚠️ VAPID keys not configured, push notifications disabled")
 	}
 
-	wsConns := repositories.NewConnections()
-	repositories.HandleObsoleteRooms(ctx, roomRepo, cfg.RoomConfig)
+	signalBus, err := bus.New(ctx, cfg.Bus)
+	if err != nil {
+		return err
+	}
+	defer signalBus.Close()
+
+	wsConns := repositories.NewConnections(ctx, cfg, signalBus, roomRepo, chatRepo, callRepo)
+	repositories.HandleObsoleteRooms(ctx, roomRepo, chatRepo, cfg.RoomConfig)
 
-	apiUseCases := usecase.NewApiUseCases(ctx, roomRepo, userRepo, cfg, jwt, refreshTokenService, pushService, wsConns)
-	signalingUseCases := usecase.NewSignalingUseCases(ctx, wsConns, jwt, pushService)
+	apiUseCases := usecase.NewApiUseCases(ctx, roomRepo, userRepo, chatRepo, callRepo, cfg, jwt, refreshTokenService, signalTickets, pushService, wsConns)
+	signalingUseCases := usecase.NewSignalingUseCases(ctx, roomRepo, cfg, wsConns, jwt, signalTickets, pushService)
 
 	httpService := restApi.NewAPI(apiUseCases)
 	httpService.RegisterHandlers()
@@ -0,0 +1,28 @@
+package entity
+
+import "time"
+
+// Call is a session of a room from the first participant connecting till the last one leaving
+type Call struct {
+	ID        int64
+	RoomID    string
+	StartedAt time.Time
+	// EndedAt is zero while the call is in progress
+	EndedAt      time.Time
+	Duration     time.Duration
+	Participants []CallParticipant
+}
+
+// CallParticipant is a device that took part in the call, a user rejoining gets another record
+type CallParticipant struct {
+	SessionID string
+	UserID    string
+	Username  string
+	JoinedAt  time.Time
+	// LeftAt is zero while the device is connected
+	LeftAt time.Time
+}
+
+func (c *Call) Active() bool {
+	return c.EndedAt.IsZero()
+}
@@ -0,0 +1,12 @@
+package entity
+
+import "time"
+
+type ChatMessage struct {
+	ID        int64
+	RoomID    string
+	UserID    string
+	Username  string
+	Text      string
+	CreatedAt time.Time
+}
@@ -0,0 +1,146 @@
+package entity
+
+import (
+	"slices"
+	"time"
+)
+
+// Recurrence frequencies
+const (
+	FreqDaily   = "daily"
+	FreqWeekly  = "weekly"
+	FreqMonthly = "monthly"
+)
+
+// maxOccurrenceScan stops expanding a series that produces nothing, e.g. monthly on the 31st with interval 2 over short months
+const maxOccurrenceScan = 10000
+
+// Recurrence repeats a scheduled meeting like RRULE does. Series ends after Count occurrences or at Until, runs forever without both
+type Recurrence struct {
+	Freq     string
+	Interval int
+	// ByDay lists weekdays of a weekly series, weekday of the first meeting when empty
+	ByDay []time.Weekday
+	Count int
+	// Until is the latest start of an occurrence
+	Until time.Time
+	// Exceptions are starts of cancelled occurrences
+	Exceptions []time.Time
+}
+
+// Endless reports whether the meeting repeats forever
+func (s *Schedule) Endless() bool {
+	return s.Recurrence != nil && s.Recurrence.Count == 0 && s.Recurrence.Until.IsZero()
+}
+
+// each calls fn with start of every occurrence in order, cancelled ones included, until fn returns false or the series ends.
+// Occurrences are computed on the wall clock of the meeting time zone, so that the meeting keeps its local time over DST changes
+func (s *Schedule) each(fn func(start time.Time) bool) {
+	rec := s.Recurrence
+	if rec == nil {
+		fn(s.StartsAt)
+		return
+	}
+
+	interval := max(rec.Interval, 1)
+	first := s.StartsAt.In(s.Location())
+	y, m, d := first.Date()
+	hh, mm, ss := first.Clock()
+
+	days := rec.ByDay
+	if len(days) == 0 {
+		days = []time.Weekday{first.Weekday()}
+	}
+	// weeks start on Monday
+	offsets := make([]int, 0, len(days))
+	for _, day := range days {
+		offsets = append(offsets, (int(day)+6)%7)
+	}
+	slices.Sort(offsets)
+	weekStart := d - (int(first.Weekday())+6)%7
+
+	count := 0
+	emit := func(start time.Time) bool {
+		if start.Before(s.StartsAt) {
+			return true
+		}
+		if !rec.Until.IsZero() && start.After(rec.Until) {
+			return false
+		}
+		count++
+		if !fn(start) {
+			return false
+		}
+
+		return rec.Count == 0 || count < rec.Count
+	}
+
+	for period := 0; period < maxOccurrenceScan; period++ {
+		switch rec.Freq {
+		case FreqDaily:
+			if !emit(time.Date(y, m, d+period*interval, hh, mm, ss, 0, first.Location())) {
+				return
+			}
+		case FreqWeekly:
+			for _, offset := range offsets {
+				if !emit(time.Date(y, m, weekStart+period*7*interval+offset, hh, mm, ss, 0, first.Location())) {
+					return
+				}
+			}
+		case FreqMonthly:
+			start := time.Date(y, m+time.Month(period*interval), d, hh, mm, ss, 0, first.Location())
+			// months without such day are skipped
+			if start.Day() == d && !emit(start) {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+// Cancelled reports whether the occurrence starting at start is an exception of the series
+func (s *Schedule) Cancelled(start time.Time) bool {
+	if s.Recurrence == nil {
+		return false
+	}
+
+	return slices.ContainsFunc(s.Recurrence.Exceptions, start.Equal)
+}
+
+// Occurrences returns starts of meetings overlapping [from, to), at most limit of them
+func (s *Schedule) Occurrences(from, to time.Time, limit int) []time.Time {
+	return s.OccurrencesAfter(from, to, time.Time{}, limit)
+}
+
+// OccurrencesAfter is Occurrences skipping meetings that start at or before after.
+// Starts of a series are distinct, so the last returned start is an exact cursor of the next page
+func (s *Schedule) OccurrencesAfter(from, to, after time.Time, limit int) []time.Time {
+	var starts []time.Time
+	s.each(func(start time.Time) bool {
+		if !start.Before(to) {
+			return false
+		}
+		if start.After(after) && start.Add(s.Duration).After(from) && !s.Cancelled(start) {
+			starts = append(starts, start)
+		}
+
+		return len(starts) < limit
+	})
+
+	return starts
+}
+
+// lastStart returns start of the last meeting of a series that is not endless
+func (s *Schedule) lastStart() time.Time {
+	last := s.StartsAt
+	s.each(func(start time.Time) bool {
+		if !s.Cancelled(start) {
+			last = start
+		}
+
+		return true
+	})
+
+	return last
+}
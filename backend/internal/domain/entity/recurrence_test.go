@@ -0,0 +1,196 @@
+package entity
+
+import (
+	"slices"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+// farFuture is the open end of a range of occurrences
+var farFuture = time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("load %s: %v", name, err)
+	}
+
+	return loc
+}
+
+func TestOccurrences(t *testing.T) {
+	berlin := mustLoad(t, "Europe/Berlin")
+	newYork := mustLoad(t, "America/New_York")
+
+	utc := func(month time.Month, day, hour int) time.Time {
+		return time.Date(2026, month, day, hour, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name     string
+		start    time.Time
+		timeZone string
+		rec      *Recurrence
+		want     []time.Time
+	}{
+		{
+			name:  "single meeting",
+			start: utc(1, 1, 10),
+			want:  []time.Time{utc(1, 1, 10)},
+		},
+		{
+			name:  "daily every other day",
+			start: utc(1, 1, 10),
+			rec:   &Recurrence{Freq: FreqDaily, Interval: 2, Count: 3},
+			want:  []time.Time{utc(1, 1, 10), utc(1, 3, 10), utc(1, 5, 10)},
+		},
+		{
+			name:  "daily until is inclusive",
+			start: utc(1, 1, 10),
+			rec:   &Recurrence{Freq: FreqDaily, Until: utc(1, 3, 10)},
+			want:  []time.Time{utc(1, 1, 10), utc(1, 2, 10), utc(1, 3, 10)},
+		},
+		{
+			name:  "daily over month end",
+			start: utc(1, 30, 10),
+			rec:   &Recurrence{Freq: FreqDaily, Count: 3},
+			want:  []time.Time{utc(1, 30, 10), utc(1, 31, 10), utc(2, 1, 10)},
+		},
+		{
+			name:  "weekly on several days",
+			start: utc(1, 5, 10), // Monday
+			rec:   &Recurrence{Freq: FreqWeekly, ByDay: []time.Weekday{time.Friday, time.Monday, time.Wednesday}, Count: 5},
+			want:  []time.Time{utc(1, 5, 10), utc(1, 7, 10), utc(1, 9, 10), utc(1, 12, 10), utc(1, 14, 10)},
+		},
+		{
+			name:  "biweekly starting mid-week",
+			start: utc(1, 8, 10), // Thursday, Tuesday of that week is before the start
+			rec:   &Recurrence{Freq: FreqWeekly, Interval: 2, ByDay: []time.Weekday{time.Tuesday, time.Thursday}, Count: 3},
+			want:  []time.Time{utc(1, 8, 10), utc(1, 20, 10), utc(1, 22, 10)},
+		},
+		{
+			name:  "weekly over year end",
+			start: utc(12, 28, 10),
+			rec:   &Recurrence{Freq: FreqWeekly, Count: 2},
+			want:  []time.Time{utc(12, 28, 10), time.Date(2027, 1, 4, 10, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:  "monthly skips months without the day",
+			start: utc(1, 31, 10),
+			rec:   &Recurrence{Freq: FreqMonthly, Count: 4},
+			want:  []time.Time{utc(1, 31, 10), utc(3, 31, 10), utc(5, 31, 10), utc(7, 31, 10)},
+		},
+		{
+			name:  "quarterly",
+			start: utc(2, 15, 10),
+			rec:   &Recurrence{Freq: FreqMonthly, Interval: 3, Count: 3},
+			want:  []time.Time{utc(2, 15, 10), utc(5, 15, 10), utc(8, 15, 10)},
+		},
+		{
+			name:  "exceptions are counted but not returned",
+			start: utc(1, 1, 10),
+			rec:   &Recurrence{Freq: FreqDaily, Count: 4, Exceptions: []time.Time{utc(1, 2, 10)}},
+			want:  []time.Time{utc(1, 1, 10), utc(1, 3, 10), utc(1, 4, 10)},
+		},
+		{
+			name:     "local time kept when daylight saving starts",
+			start:    time.Date(2026, 3, 28, 9, 0, 0, 0, berlin),
+			timeZone: "Europe/Berlin",
+			rec:      &Recurrence{Freq: FreqDaily, Count: 3},
+			want:     []time.Time{utc(3, 28, 8), utc(3, 29, 7), utc(3, 30, 7)},
+		},
+		{
+			name:     "local time kept when daylight saving ends",
+			start:    time.Date(2026, 10, 26, 9, 0, 0, 0, newYork),
+			timeZone: "America/New_York",
+			rec:      &Recurrence{Freq: FreqWeekly, Count: 2},
+			want:     []time.Time{utc(10, 26, 13), utc(11, 2, 14)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Schedule{StartsAt: tt.start, Duration: time.Hour, TimeZone: tt.timeZone, Recurrence: tt.rec}
+
+			got := s.Occurrences(time.Time{}, farFuture, 100)
+			if !slices.EqualFunc(got, tt.want, time.Time.Equal) {
+				t.Fatalf("occurrences\n got %v\nwant %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOccurrencesRange(t *testing.T) {
+	start := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	s := &Schedule{StartsAt: start, Duration: time.Hour, Recurrence: &Recurrence{Freq: FreqDaily}}
+
+	// the meeting of January 2 is in progress at from, the one of January 4 starts at to
+	from := time.Date(2026, 1, 2, 10, 30, 0, 0, time.UTC)
+	to := time.Date(2026, 1, 4, 10, 0, 0, 0, time.UTC)
+
+	got := s.Occurrences(from, to, 100)
+	want := []time.Time{start.AddDate(0, 0, 1), start.AddDate(0, 0, 2)}
+	if !slices.EqualFunc(got, want, time.Time.Equal) {
+		t.Fatalf("occurrences %v, want %v", got, want)
+	}
+
+	if got := s.Occurrences(from, to, 1); len(got) != 1 {
+		t.Fatalf("limit 1 returned %d occurrences", len(got))
+	}
+}
+
+func TestOccurrencesAfterPaging(t *testing.T) {
+	s := &Schedule{
+		StartsAt: time.Date(2026, 1, 5, 10, 0, 0, 0, time.UTC),
+		Duration: time.Hour,
+		Recurrence: &Recurrence{
+			Freq:       FreqWeekly,
+			ByDay:      []time.Weekday{time.Monday, time.Thursday},
+			Exceptions: []time.Time{time.Date(2026, 1, 12, 10, 0, 0, 0, time.UTC)},
+		},
+	}
+	want := s.Occurrences(time.Time{}, farFuture, 10)
+
+	var got []time.Time
+	var after time.Time
+	for len(got) < len(want) {
+		page := s.OccurrencesAfter(time.Time{}, farFuture, after, 3)
+		if len(page) == 0 {
+			t.Fatalf("empty page after %v", after)
+		}
+		got = append(got, page...)
+		after = page[len(page)-1]
+	}
+
+	if !slices.EqualFunc(got[:len(want)], want, time.Time.Equal) {
+		t.Fatalf("paged occurrences\n got %v\nwant %v", got, want)
+	}
+}
+
+func TestEndsAt(t *testing.T) {
+	start := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		rec  *Recurrence
+		want time.Time
+	}{
+		{"single meeting", nil, start.Add(time.Hour)},
+		{"endless series", &Recurrence{Freq: FreqDaily}, time.Time{}},
+		{"count", &Recurrence{Freq: FreqDaily, Count: 3}, start.AddDate(0, 0, 2).Add(time.Hour)},
+		{"until", &Recurrence{Freq: FreqWeekly, Until: start.AddDate(0, 0, 20)}, start.AddDate(0, 0, 14).Add(time.Hour)},
+		{"last meeting cancelled", &Recurrence{Freq: FreqDaily, Count: 3, Exceptions: []time.Time{start.AddDate(0, 0, 2)}}, start.AddDate(0, 0, 1).Add(time.Hour)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Schedule{StartsAt: start, Duration: time.Hour, Recurrence: tt.rec}
+			if got := s.EndsAt(); !got.Equal(tt.want) {
+				t.Fatalf("EndsAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -5,7 +5,31 @@ import (
 )
 
 type Room struct {
+	ID    string
+	Title string
+	// Slug is a human-readable alias of the room ID, rooms with slug are kept by cleanup
+	Slug          string
 	CreatedAt     time.Time
 	UpdatedAt     time.Time
 	CreatorUserID string
+	Capacity      int
+	// Lobby makes join requests wait for the host to admit them
+	Lobby bool
+	// PasscodeHash is bcrypt hash of the join passcode, empty when not set
+	PasscodeHash string
+	// Locked rooms accept no new participants
+	Locked bool
+	// Schedule of a planned meeting, nil for ad hoc rooms
+	Schedule *Schedule
+}
+
+// Persistent reports whether the room outlives a meeting held in it: a recurring series
+// and a room with slug keep their link for the next meeting
+func (r *Room) Persistent() bool {
+	return r.Slug != "" || r.Schedule != nil && r.Schedule.Recurrence != nil
+}
+
+// Restricted reports whether the room admits only some users, so that its details are hidden from others
+func (r *Room) Restricted() bool {
+	return r.Lobby || r.Locked || r.PasscodeHash != ""
 }
@@ -0,0 +1,38 @@
+package entity
+
+import "time"
+
+// Schedule is the plan of a meeting held in a room
+type Schedule struct {
+	StartsAt time.Time
+	Duration time.Duration
+	// TimeZone is IANA name of the zone the meeting was planned in
+	TimeZone string
+	// Invitees are IDs of invited users
+	Invitees []string
+	// Recurrence repeats the meeting in the same room, nil for a single meeting
+	Recurrence *Recurrence
+}
+
+// EndsAt returns end of the meeting or of the last meeting of the series, zero when the series is endless
+func (s *Schedule) EndsAt() time.Time {
+	if s.Recurrence == nil {
+		return s.StartsAt.Add(s.Duration)
+	}
+
+	if s.Endless() {
+		return time.Time{}
+	}
+
+	return s.lastStart().Add(s.Duration)
+}
+
+// Location returns time zone of the meeting, UTC when unknown
+func (s *Schedule) Location() *time.Location {
+	loc, err := time.LoadLocation(s.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+
+	return loc
+}
@@ -0,0 +1,260 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"videocall/internal/domain/entity"
+	"videocall/internal/domain/repositories"
+)
+
+type MariaDBCallRepository struct {
+	db *sql.DB
+}
+
+func NewMariaDBCallRepository(db *sql.DB) *MariaDBCallRepository {
+	return &MariaDBCallRepository{db: db}
+}
+
+func (r *MariaDBCallRepository) JoinCall(roomID string, p entity.CallParticipant) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to join call: %w", err)
+	}
+	defer tx.Rollback()
+
+	// another instance may have opened the call already, LAST_INSERT_ID then returns its ID
+	res, err := tx.Exec(`
+		INSERT INTO calls (room_id, active_room_id, started_at) VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE id = LAST_INSERT_ID(id)
+	`, roomID, roomID, p.JoinedAt)
+	if err != nil {
+		return fmt.Errorf("failed to open call: %w", err)
+	}
+
+	callID, err := res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get call id: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		INSERT IGNORE INTO call_participants (call_id, session_id, user_id, username, joined_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, callID, p.SessionID, p.UserID, p.Username, p.JoinedAt)
+	if err != nil {
+		return fmt.Errorf("failed to add call participant: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to join call: %w", err)
+	}
+
+	return nil
+}
+
+func (r *MariaDBCallRepository) LeaveCall(roomID, sessionID string, at time.Time) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to leave call: %w", err)
+	}
+	defer tx.Rollback()
+
+	var callID int64
+	err = tx.QueryRow(`SELECT id FROM calls WHERE active_room_id = ? FOR UPDATE`, roomID).Scan(&callID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("failed to find call: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		UPDATE call_participants SET left_at = ?
+		WHERE call_id = ? AND session_id = ? AND left_at IS NULL
+	`, at, callID, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to update call participant: %w", err)
+	}
+
+	var connected int
+	err = tx.QueryRow(`SELECT COUNT(*) FROM call_participants WHERE call_id = ? AND left_at IS NULL`, callID).Scan(&connected)
+	if err != nil {
+		return fmt.Errorf("failed to count call participants: %w", err)
+	}
+
+	if connected == 0 {
+		_, err = tx.Exec(`
+			UPDATE calls SET active_room_id = NULL, ended_at = ?, duration_seconds = TIMESTAMPDIFF(SECOND, started_at, ?)
+			WHERE id = ?
+		`, at, at, callID)
+		if err != nil {
+			return fmt.Errorf("failed to end call: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to leave call: %w", err)
+	}
+
+	return nil
+}
+
+func (r *MariaDBCallRepository) CloseStaleCalls(live map[string]bool, joinedBefore, at time.Time) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to close stale calls: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`
+		SELECT p.call_id, p.session_id FROM call_participants p
+		JOIN calls c ON c.id = p.call_id
+		WHERE c.active_room_id IS NOT NULL AND p.left_at IS NULL AND p.joined_at < ?
+		FOR UPDATE
+	`, joinedBefore)
+	if err != nil {
+		return fmt.Errorf("failed to find open call participants: %w", err)
+	}
+
+	type participant struct {
+		callID    int64
+		sessionID string
+	}
+	var stale []participant
+	for rows.Next() {
+		var p participant
+		if err := rows.Scan(&p.callID, &p.sessionID); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan call participant: %w", err)
+		}
+		if !live[p.sessionID] {
+			stale = append(stale, p)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to find open call participants: %w", err)
+	}
+
+	for _, p := range stale {
+		_, err = tx.Exec(`
+			UPDATE call_participants SET left_at = ?
+			WHERE call_id = ? AND session_id = ? AND left_at IS NULL
+		`, at, p.callID, p.sessionID)
+		if err != nil {
+			return fmt.Errorf("failed to update call participant: %w", err)
+		}
+	}
+
+	_, err = tx.Exec(`
+		UPDATE calls SET active_room_id = NULL, ended_at = ?, duration_seconds = TIMESTAMPDIFF(SECOND, started_at, ?)
+		WHERE active_room_id IS NOT NULL
+		AND NOT EXISTS (SELECT 1 FROM call_participants WHERE call_id = calls.id AND left_at IS NULL)
+	`, at, at)
+	if err != nil {
+		return fmt.Errorf("failed to end stale calls: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to close stale calls: %w", err)
+	}
+
+	return nil
+}
+
+func (r *MariaDBCallRepository) ListCalls(filter repositories.CallFilter) ([]*entity.Call, error) {
+	var where []string
+	var args []any
+
+	if filter.UserID != "" {
+		where = append(where, `id IN (SELECT call_id FROM call_participants WHERE user_id = ?)`)
+		args = append(args, filter.UserID)
+	}
+	if filter.RoomID != "" {
+		where = append(where, `room_id = ?`)
+		args = append(args, filter.RoomID)
+	}
+	if !filter.From.IsZero() {
+		where = append(where, `started_at >= ?`)
+		args = append(args, filter.From)
+	}
+	if !filter.To.IsZero() {
+		where = append(where, `started_at < ?`)
+		args = append(args, filter.To)
+	}
+	if filter.BeforeID > 0 {
+		where = append(where, `id < ?`)
+		args = append(args, filter.BeforeID)
+	}
+
+	query := `SELECT id, room_id, started_at, ended_at, duration_seconds FROM calls`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, ` AND `)
+	}
+	query += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, filter.Limit)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list calls: %w", err)
+	}
+
+	var calls []*entity.Call
+	byID := make(map[int64]*entity.Call)
+	for rows.Next() {
+		var call entity.Call
+		var endedAt sql.NullTime
+		var durationSeconds int64
+		if err := rows.Scan(&call.ID, &call.RoomID, &call.StartedAt, &endedAt, &durationSeconds); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan call: %w", err)
+		}
+		call.EndedAt = endedAt.Time
+		call.Duration = time.Duration(durationSeconds) * time.Second
+		calls = append(calls, &call)
+		byID[call.ID] = &call
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list calls: %w", err)
+	}
+
+	if len(calls) == 0 {
+		return calls, nil
+	}
+
+	ids := make([]any, 0, len(calls))
+	for _, call := range calls {
+		ids = append(ids, call.ID)
+	}
+
+	rows, err = r.db.Query(`
+		SELECT call_id, session_id, user_id, username, joined_at, left_at
+		FROM call_participants
+		WHERE call_id IN (?`+strings.Repeat(`, ?`, len(ids)-1)+`)
+		ORDER BY joined_at
+	`, ids...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list call participants: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var callID int64
+		var p entity.CallParticipant
+		var leftAt sql.NullTime
+		if err := rows.Scan(&callID, &p.SessionID, &p.UserID, &p.Username, &p.JoinedAt, &leftAt); err != nil {
+			return nil, fmt.Errorf("failed to scan call participant: %w", err)
+		}
+		p.LeftAt = leftAt.Time
+		byID[callID].Participants = append(byID[callID].Participants, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list call participants: %w", err)
+	}
+
+	return calls, nil
+}
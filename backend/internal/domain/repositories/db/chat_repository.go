@@ -0,0 +1,76 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+
+	"videocall/internal/domain/entity"
+)
+
+type MariaDBChatRepository struct {
+	db *sql.DB
+}
+
+func NewMariaDBChatRepository(db *sql.DB) *MariaDBChatRepository {
+	return &MariaDBChatRepository{db: db}
+}
+
+func (r *MariaDBChatRepository) AddMessage(msg *entity.ChatMessage) error {
+	query := `
+		INSERT INTO chat_messages (room_id, user_id, username, text, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`
+	res, err := r.db.Exec(query, msg.RoomID, msg.UserID, msg.Username, msg.Text, msg.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to add chat message: %w", err)
+	}
+
+	msg.ID, err = res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get chat message id: %w", err)
+	}
+
+	return nil
+}
+
+func (r *MariaDBChatRepository) ListMessages(roomID string, beforeID int64, limit int) ([]*entity.ChatMessage, error) {
+	query := `
+		SELECT id, room_id, user_id, username, text, created_at
+		FROM chat_messages
+		WHERE room_id = ? AND (? = 0 OR id < ?)
+		ORDER BY id DESC
+		LIMIT ?
+	`
+	rows, err := r.db.Query(query, roomID, beforeID, beforeID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chat messages: %w", err)
+	}
+	defer rows.Close()
+
+	var msgs []*entity.ChatMessage
+	for rows.Next() {
+		var msg entity.ChatMessage
+		if err := rows.Scan(&msg.ID, &msg.RoomID, &msg.UserID, &msg.Username, &msg.Text, &msg.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan chat message: %w", err)
+		}
+		msgs = append(msgs, &msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list chat messages: %w", err)
+	}
+
+	// newest were selected first, history is returned in chronological order
+	slices.Reverse(msgs)
+
+	return msgs, nil
+}
+
+func (r *MariaDBChatRepository) DeleteRoomMessages(roomID string) error {
+	_, err := r.db.Exec(`DELETE FROM chat_messages WHERE room_id = ?`, roomID)
+	if err != nil {
+		return fmt.Errorf("failed to delete chat messages: %w", err)
+	}
+
+	return nil
+}
@@ -2,11 +2,13 @@ package db
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"log"
 	"time"
 
 	"videocall/internal/domain/entity"
+	"videocall/internal/domain/repositories"
 )
 
 type MariaDBRoomRepository struct {
@@ -18,28 +20,149 @@ func NewMariaDBRoomRepository(db *sql.DB) *MariaDBRoomRepository {
 	return repo
 }
 
-func (r *MariaDBRoomRepository) AddRoom(roomID, creatorUserID string) {
+const roomColumns = `id, title, slug, creator_user_id, capacity, lobby, passcode_hash, locked, starts_at, duration_seconds, time_zone, recurrence, created_at, updated_at`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanRoom(row rowScanner) (*entity.Room, error) {
+	var room entity.Room
+	var startsAt sql.NullTime
+	var durationSeconds int64
+	var timeZone string
+	var recurrenceJSON []byte
+	var slug sql.NullString
+
+	err := row.Scan(&room.ID, &room.Title, &slug, &room.CreatorUserID, &room.Capacity, &room.Lobby, &room.PasscodeHash, &room.Locked,
+		&startsAt, &durationSeconds, &timeZone, &recurrenceJSON, &room.CreatedAt, &room.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	room.Slug = slug.String
+
+	if startsAt.Valid {
+		room.Schedule = &entity.Schedule{
+			StartsAt: startsAt.Time,
+			Duration: time.Duration(durationSeconds) * time.Second,
+			TimeZone: timeZone,
+		}
+		if recurrenceJSON != nil {
+			var recurrence entity.Recurrence
+			if err := json.Unmarshal(recurrenceJSON, &recurrence); err != nil {
+				return nil, err
+			}
+			room.Schedule.Recurrence = &recurrence
+		}
+	}
+
+	return &room, nil
+}
+
+// scheduleArgs returns values of starts_at, duration_seconds, time_zone, recurrence and ends_at columns
+func scheduleArgs(schedule *entity.Schedule) ([]any, error) {
+	if schedule == nil {
+		return []any{nil, 0, "", nil, nil}, nil
+	}
+
+	var recurrenceJSON []byte
+	if schedule.Recurrence != nil {
+		var err error
+		if recurrenceJSON, err = json.Marshal(schedule.Recurrence); err != nil {
+			return nil, err
+		}
+	}
+
+	// endless series is never cleaned up
+	var endsAt any
+	if !schedule.Endless() {
+		endsAt = schedule.EndsAt().UTC()
+	}
+
+	return []any{schedule.StartsAt.UTC(), int64(schedule.Duration / time.Second), schedule.TimeZone, recurrenceJSON, endsAt}, nil
+}
+
+func (r *MariaDBRoomRepository) loadInvitees(room *entity.Room) error {
+	if room.Schedule == nil {
+		return nil
+	}
+
+	rows, err := r.db.Query(`SELECT user_id FROM room_invitees WHERE room_id = ?`, room.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return err
+		}
+		room.Schedule.Invitees = append(room.Schedule.Invitees, userID)
+	}
+
+	return rows.Err()
+}
+
+func saveInvitees(tx *sql.Tx, room *entity.Room) error {
+	if _, err := tx.Exec(`DELETE FROM room_invitees WHERE room_id = ?`, room.ID); err != nil {
+		return err
+	}
+
+	if room.Schedule == nil {
+		return nil
+	}
+
+	for _, userID := range room.Schedule.Invitees {
+		if _, err := tx.Exec(`INSERT IGNORE INTO room_invitees (room_id, user_id) VALUES (?, ?)`, room.ID, userID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *MariaDBRoomRepository) AddRoom(room *entity.Room) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		log.Printf("error adding room: %v", err)
+		return
+	}
+	defer tx.Rollback()
+
 	query := `
-		INSERT INTO rooms (id, creator_user_id, created_at, updated_at)
-		VALUES (?, ?, ?, ?)
+		INSERT INTO rooms (id, title, creator_user_id, capacity, lobby, passcode_hash, starts_at, duration_seconds, time_zone, recurrence, ends_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON DUPLICATE KEY UPDATE updated_at = VALUES(updated_at)
 	`
-	_, err := r.db.Exec(query, roomID, creatorUserID, time.Now(), time.Now())
+	schedule, err := scheduleArgs(room.Schedule)
 	if err != nil {
 		log.Printf("error adding room: %v", err)
+		return
+	}
+	args := []any{room.ID, room.Title, room.CreatorUserID, room.Capacity, room.Lobby, room.PasscodeHash}
+	args = append(args, schedule...)
+	args = append(args, time.Now(), time.Now())
+	if _, err := tx.Exec(query, args...); err != nil {
+		log.Printf("error adding room: %v", err)
+		return
+	}
+
+	if err := saveInvitees(tx, room); err != nil {
+		log.Printf("error adding room invitees: %v", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("error adding room: %v", err)
 	}
 }
 
 func (r *MariaDBRoomRepository) GetRoom(roomID string) (*entity.Room, bool) {
-	query := `
-		SELECT id, creator_user_id, created_at, updated_at
-		FROM rooms
-		WHERE id = ?
-	`
-	var roomIDDB, creatorUserID string
-	var createdAt, updatedAt time.Time
+	query := `SELECT ` + roomColumns + ` FROM rooms WHERE id = ?`
 
-	err := r.db.QueryRow(query, roomID).Scan(&roomIDDB, &creatorUserID, &createdAt, &updatedAt)
+	room, err := scanRoom(r.db.QueryRow(query, roomID))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, false
@@ -48,11 +171,103 @@ func (r *MariaDBRoomRepository) GetRoom(roomID string) (*entity.Room, bool) {
 		return nil, false
 	}
 
-	return &entity.Room{
-		CreatedAt:     createdAt,
-		UpdatedAt:     updatedAt,
-		CreatorUserID: creatorUserID,
-	}, true
+	if err := r.loadInvitees(room); err != nil {
+		log.Printf("error getting room invitees: %v", err)
+		return nil, false
+	}
+
+	return room, true
+}
+
+func (r *MariaDBRoomRepository) UpdateRoom(room *entity.Room) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		log.Printf("error updating room: %v", err)
+		return
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE rooms SET title = ?, capacity = ?, lobby = ?, locked = ?, passcode_hash = ?,
+			starts_at = ?, duration_seconds = ?, time_zone = ?, recurrence = ?, ends_at = ?
+		WHERE id = ?
+	`
+	schedule, err := scheduleArgs(room.Schedule)
+	if err != nil {
+		log.Printf("error updating room: %v", err)
+		return
+	}
+	args := []any{room.Title, room.Capacity, room.Lobby, room.Locked, room.PasscodeHash}
+	args = append(args, schedule...)
+	args = append(args, room.ID)
+	if _, err := tx.Exec(query, args...); err != nil {
+		log.Printf("error updating room: %v", err)
+		return
+	}
+
+	if err := saveInvitees(tx, room); err != nil {
+		log.Printf("error updating room invitees: %v", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("error updating room: %v", err)
+	}
+}
+
+func (r *MariaDBRoomRepository) AddMember(roomID, userID string) {
+	query := `INSERT IGNORE INTO room_members (room_id, user_id, joined_at) VALUES (?, ?, ?)`
+	_, err := r.db.Exec(query, roomID, userID, time.Now())
+	if err != nil {
+		log.Printf("error adding room member: %v", err)
+	}
+}
+
+func (r *MariaDBRoomRepository) IsMember(roomID, userID string) bool {
+	var member bool
+	query := `SELECT EXISTS(SELECT 1 FROM room_members WHERE room_id = ? AND user_id = ?)`
+	if err := r.db.QueryRow(query, roomID, userID).Scan(&member); err != nil {
+		log.Printf("error checking room member: %v", err)
+		return false
+	}
+
+	return member
+}
+
+func (r *MariaDBRoomRepository) ListRooms(userID string) []*entity.Room {
+	query := `
+		SELECT ` + roomColumns + ` FROM rooms
+		WHERE creator_user_id = ?
+			OR id IN (SELECT room_id FROM room_members WHERE user_id = ?)
+			OR id IN (SELECT room_id FROM room_invitees WHERE user_id = ?)
+		ORDER BY updated_at DESC
+	`
+	rows, err := r.db.Query(query, userID, userID, userID)
+	if err != nil {
+		log.Printf("error listing rooms: %v", err)
+		return nil
+	}
+
+	var rooms []*entity.Room
+	for rows.Next() {
+		room, err := scanRoom(rows)
+		if err != nil {
+			rows.Close()
+			log.Printf("error listing rooms: %v", err)
+			return nil
+		}
+		rooms = append(rooms, room)
+	}
+	rows.Close()
+
+	for _, room := range rooms {
+		if err := r.loadInvitees(room); err != nil {
+			log.Printf("error listing room invitees: %v", err)
+			return nil
+		}
+	}
+
+	return rooms
 }
 
 func (r *MariaDBRoomRepository) RefreshRoom(roomID string) {
@@ -63,10 +278,131 @@ func (r *MariaDBRoomRepository) RefreshRoom(roomID string) {
 	}
 }
 
-func (r *MariaDBRoomRepository) CleanRooms(ttl time.Duration) {
-	query := `DELETE FROM rooms WHERE updated_at <= ?`
-	_, err := r.db.Exec(query, time.Now().Add(-ttl))
+func (r *MariaDBRoomRepository) BanUser(roomID, userID string) {
+	query := `INSERT IGNORE INTO room_bans (room_id, user_id, banned_at) VALUES (?, ?, ?)`
+	_, err := r.db.Exec(query, roomID, userID, time.Now())
+	if err != nil {
+		log.Printf("error banning room member: %v", err)
+	}
+}
+
+func (r *MariaDBRoomRepository) IsBanned(roomID, userID string) bool {
+	var banned bool
+	query := `SELECT EXISTS(SELECT 1 FROM room_bans WHERE room_id = ? AND user_id = ?)`
+	if err := r.db.QueryRow(query, roomID, userID).Scan(&banned); err != nil {
+		log.Printf("error checking room ban: %v", err)
+		return false
+	}
+
+	return banned
+}
+
+func (r *MariaDBRoomRepository) SetPasscode(roomID, passcodeHash string) {
+	_, err := r.db.Exec(`UPDATE rooms SET passcode_hash = ? WHERE id = ?`, passcodeHash, roomID)
+	if err != nil {
+		log.Printf("error setting room passcode: %v", err)
+	}
+}
+
+func (r *MariaDBRoomRepository) SetLocked(roomID string, locked bool) {
+	_, err := r.db.Exec(`UPDATE rooms SET locked = ? WHERE id = ?`, locked, roomID)
+	if err != nil {
+		log.Printf("error locking room: %v", err)
+	}
+}
+
+func (r *MariaDBRoomRepository) GetRoomBySlug(slug string) (*entity.Room, bool) {
+	query := `SELECT ` + roomColumns + ` FROM rooms WHERE slug = ?`
+
+	room, err := scanRoom(r.db.QueryRow(query, slug))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, false
+		}
+		log.Printf("error getting room by slug: %v", err)
+		return nil, false
+	}
+
+	if err := r.loadInvitees(room); err != nil {
+		log.Printf("error getting room invitees: %v", err)
+		return nil, false
+	}
+
+	return room, true
+}
+
+func (r *MariaDBRoomRepository) SetSlug(roomID, slug string) error {
+	// NULL keeps unique index free of released slugs
+	var value any
+	if slug != "" {
+		value = slug
+	}
+
+	_, err := r.db.Exec(`UPDATE rooms SET slug = ? WHERE id = ?`, value, roomID)
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			return repositories.ErrSlugTaken
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (r *MariaDBRoomRepository) SetOwner(roomID, userID string) {
+	_, err := r.db.Exec(`UPDATE rooms SET creator_user_id = ? WHERE id = ?`, userID, roomID)
+	if err != nil {
+		log.Printf("error changing room owner: %v", err)
+	}
+}
+
+func (r *MariaDBRoomRepository) DeleteRoom(roomID string) {
+	_, err := r.db.Exec(`DELETE FROM rooms WHERE id = ?`, roomID)
 	if err != nil {
 		log.Printf("error deleting room: %v", err)
 	}
 }
+
+func (r *MariaDBRoomRepository) CleanRooms(ttl time.Duration) []string {
+	tx, err := r.db.Begin()
+	if err != nil {
+		log.Printf("error deleting room: %v", err)
+		return nil
+	}
+	defer tx.Rollback()
+
+	// scheduled meeting is kept as if it was last active at its end, endless series (no ends_at) for good
+	// rooms with slug live while they have it, deleting the owner deletes them by foreign key
+	const obsolete = `updated_at <= ? AND slug IS NULL AND (starts_at IS NULL OR (ends_at IS NOT NULL AND ends_at <= ?))`
+
+	threshold := time.Now().Add(-ttl)
+	rows, err := tx.Query(`SELECT id FROM rooms WHERE `+obsolete+` FOR UPDATE`, threshold, threshold)
+	if err != nil {
+		log.Printf("error deleting room: %v", err)
+		return nil
+	}
+
+	var deleted []string
+	for rows.Next() {
+		var roomID string
+		if err := rows.Scan(&roomID); err != nil {
+			rows.Close()
+			log.Printf("error deleting room: %v", err)
+			return nil
+		}
+		deleted = append(deleted, roomID)
+	}
+	rows.Close()
+
+	if _, err := tx.Exec(`DELETE FROM rooms WHERE `+obsolete, threshold, threshold); err != nil {
+		log.Printf("error deleting room: %v", err)
+		return nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("error deleting room: %v", err)
+		return nil
+	}
+
+	return deleted
+}
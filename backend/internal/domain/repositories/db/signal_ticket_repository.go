@@ -0,0 +1,51 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+type MariaDBSignalTicketRepository struct {
+	db *sql.DB
+}
+
+func NewMariaDBSignalTicketRepository(ctx context.Context, db *sql.DB) *MariaDBSignalTicketRepository {
+	repo := &MariaDBSignalTicketRepository{db: db}
+	repo.handleExpiredTickets(ctx)
+	return repo
+}
+
+// MarkRedeemed relies on the primary key, so that concurrent redemptions on different instances can not both succeed
+func (r *MariaDBSignalTicketRepository) MarkRedeemed(nonce string, expiry time.Time) (bool, error) {
+	_, err := r.db.Exec(`INSERT INTO redeemed_signal_tickets (nonce, expiry) VALUES (?, ?)`, nonce, expiry)
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to redeem signal ticket: %w", err)
+	}
+
+	return true, nil
+}
+
+func (r *MariaDBSignalTicketRepository) handleExpiredTickets(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				_, err := r.db.Exec(`DELETE FROM redeemed_signal_tickets WHERE expiry < ?`, time.Now())
+				if err != nil {
+					log.Printf("Error cleaning up redeemed signal tickets: %v", err)
+				}
+			}
+		}
+	}()
+}
@@ -9,6 +9,8 @@ import (
 
 	"videocall/internal/domain/entity"
 	"videocall/internal/domain/repositories"
+
+	"github.com/go-sql-driver/mysql"
 )
 
 type MariaDBUserRepository struct {
@@ -170,9 +172,6 @@ func (r *MariaDBUserRepository) RemovePushSubscription(userID string) error {
 
 func isDuplicateKeyError(err error) bool {
 	// MariaDB/MySQL duplicate key error code
-	if err != nil {
-		errStr := err.Error()
-		return errStr != "" && (errStr == "1062" || errStr == "ER_DUP_ENTRY")
-	}
-	return false
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1062
 }
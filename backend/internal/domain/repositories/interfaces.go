@@ -12,13 +12,69 @@ var (
 	ErrTokenNotFound      = errors.New("token not found")
 	ErrUserNotFound       = errors.New("user not found")
 	ErrUserAlreadyExists  = errors.New("user already exists")
+	ErrRoomFull           = errors.New("room is full")
+	ErrSlugTaken          = errors.New("slug is taken")
 )
 
 type RoomRepositoryInterface interface {
-	AddRoom(roomID, creatorUserID string)
+	AddRoom(room *entity.Room)
 	GetRoom(roomID string) (*entity.Room, bool)
+	// UpdateRoom saves title and settings of the room
+	UpdateRoom(room *entity.Room)
+	// AddMember remembers that the user joined the room
+	AddMember(roomID, userID string)
+	// IsMember reports whether the user joined the room
+	IsMember(roomID, userID string) bool
+	// ListRooms returns rooms the user created, joined or was invited to, recently active first
+	ListRooms(userID string) []*entity.Room
 	RefreshRoom(roomID string)
-	CleanRooms(ts time.Duration)
+	DeleteRoom(roomID string)
+	SetPasscode(roomID, passcodeHash string)
+	SetLocked(roomID string, locked bool)
+	// BanUser keeps the user kicked by host out of the room, bans are deleted with the room
+	BanUser(roomID, userID string)
+	IsBanned(roomID, userID string) bool
+	// GetRoomBySlug finds room by its vanity slug
+	GetRoomBySlug(slug string) (*entity.Room, bool)
+	// SetSlug claims slug for the room, empty slug releases it. Fails with ErrSlugTaken when another room has it
+	SetSlug(roomID, slug string) error
+	// SetOwner passes the room, its slug and host rights to another user
+	SetOwner(roomID, userID string)
+	// CleanRooms deletes rooms not refreshed within ttl and returns their IDs.
+	// Scheduled rooms are kept until ttl passes after the end of the meeting, rooms with slug are kept while they have it
+	CleanRooms(ttl time.Duration) []string
+}
+
+type ChatRepositoryInterface interface {
+	// AddMessage stores message and assigns its ID
+	AddMessage(msg *entity.ChatMessage) error
+	// ListMessages returns up to limit messages older than beforeID (latest ones when beforeID is 0) in chronological order
+	ListMessages(roomID string, beforeID int64, limit int) ([]*entity.ChatMessage, error)
+	DeleteRoomMessages(roomID string) error
+}
+
+type CallRepositoryInterface interface {
+	// JoinCall records participant connecting to the room and opens a call when none is in progress
+	JoinCall(roomID string, p entity.CallParticipant) error
+	// LeaveCall records participant leaving, the call ends when nobody is left
+	LeaveCall(roomID, sessionID string, at time.Time) error
+	// CloseStaleCalls marks participants that joined before the given time and are not live as left at the given time
+	// and ends calls nobody is connected to
+	CloseStaleCalls(live map[string]bool, joinedBefore, at time.Time) error
+	// ListCalls returns calls matching the filter, newest first
+	ListCalls(filter CallFilter) ([]*entity.Call, error)
+}
+
+// CallFilter selects calls of the user. Zero fields do not filter
+type CallFilter struct {
+	UserID string
+	RoomID string
+	// From and To bound call start
+	From time.Time
+	To   time.Time
+	// BeforeID pages back from the call with this ID
+	BeforeID int64
+	Limit    int
 }
 
 type UserRepositoryInterface interface {
@@ -34,3 +90,8 @@ type RefreshTokenRepositoryInterface interface {
 	GetToken(token string) (*entity.RefreshToken, error)
 	Remove(token string)
 }
+
+type SignalTicketRepositoryInterface interface {
+	// MarkRedeemed remembers ticket nonce until expiry, reports false when the nonce was redeemed already
+	MarkRedeemed(nonce string, expiry time.Time) (bool, error)
+}
@@ -0,0 +1,145 @@
+package mem
+
+import (
+	"slices"
+	"sync"
+	"time"
+	"videocall/internal/domain/entity"
+	"videocall/internal/domain/repositories"
+)
+
+// maxCalls bounds call history kept in memory, the oldest ended calls are forgotten first
+const maxCalls = 10000
+
+type CallRepository struct {
+	mu     sync.RWMutex
+	lastID int64
+	Calls  []*entity.Call          // ordered by ID
+	active map[string]*entity.Call // room ID -> call in progress
+}
+
+func NewCallRepository() *CallRepository {
+	return &CallRepository{
+		active: make(map[string]*entity.Call),
+	}
+}
+
+func (cr *CallRepository) JoinCall(roomID string, p entity.CallParticipant) error {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+
+	call, ok := cr.active[roomID]
+	if !ok {
+		cr.lastID++
+		call = &entity.Call{
+			ID:        cr.lastID,
+			RoomID:    roomID,
+			StartedAt: p.JoinedAt,
+		}
+		cr.Calls = append(cr.Calls, call)
+		cr.active[roomID] = call
+		cr.prune()
+	}
+
+	if !slices.ContainsFunc(call.Participants, func(cur entity.CallParticipant) bool { return cur.SessionID == p.SessionID }) {
+		call.Participants = append(call.Participants, p)
+	}
+
+	return nil
+}
+
+// prune drops the oldest ended calls over maxCalls. Must be called with cr.mu held
+func (cr *CallRepository) prune() {
+	excess := len(cr.Calls) - maxCalls
+	if excess <= 0 {
+		return
+	}
+
+	cr.Calls = slices.DeleteFunc(cr.Calls, func(call *entity.Call) bool {
+		if excess > 0 && !call.EndedAt.IsZero() {
+			excess--
+			return true
+		}
+		return false
+	})
+}
+
+func (cr *CallRepository) LeaveCall(roomID, sessionID string, at time.Time) error {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+
+	call, ok := cr.active[roomID]
+	if !ok {
+		return nil
+	}
+
+	connected := 0
+	for i := range call.Participants {
+		p := &call.Participants[i]
+		if p.SessionID == sessionID && p.LeftAt.IsZero() {
+			p.LeftAt = at
+		}
+		if p.LeftAt.IsZero() {
+			connected++
+		}
+	}
+
+	if connected == 0 {
+		call.EndedAt = at
+		call.Duration = at.Sub(call.StartedAt)
+		delete(cr.active, roomID)
+	}
+
+	return nil
+}
+
+func (cr *CallRepository) CloseStaleCalls(live map[string]bool, joinedBefore, at time.Time) error {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+
+	for roomID, call := range cr.active {
+		connected := 0
+		for i := range call.Participants {
+			p := &call.Participants[i]
+			if p.LeftAt.IsZero() && !live[p.SessionID] && p.JoinedAt.Before(joinedBefore) {
+				p.LeftAt = at
+			}
+			if p.LeftAt.IsZero() {
+				connected++
+			}
+		}
+
+		if connected == 0 {
+			call.EndedAt = at
+			call.Duration = at.Sub(call.StartedAt)
+			delete(cr.active, roomID)
+		}
+	}
+
+	return nil
+}
+
+func (cr *CallRepository) ListCalls(filter repositories.CallFilter) ([]*entity.Call, error) {
+	cr.mu.RLock()
+	defer cr.mu.RUnlock()
+
+	var calls []*entity.Call
+	for i := len(cr.Calls) - 1; i >= 0 && len(calls) < filter.Limit; i-- {
+		call := cr.Calls[i]
+
+		switch {
+		case filter.BeforeID > 0 && call.ID >= filter.BeforeID,
+			filter.RoomID != "" && call.RoomID != filter.RoomID,
+			!filter.From.IsZero() && call.StartedAt.Before(filter.From),
+			!filter.To.IsZero() && !call.StartedAt.Before(filter.To),
+			filter.UserID != "" && !slices.ContainsFunc(call.Participants, func(p entity.CallParticipant) bool { return p.UserID == filter.UserID }):
+			continue
+		}
+
+		c := *call
+		c.Participants = slices.Clone(call.Participants)
+		calls = append(calls, &c)
+	}
+
+	return calls, nil
+}
@@ -0,0 +1,91 @@
+package mem
+
+import (
+	"testing"
+	"time"
+	"videocall/internal/domain/entity"
+	"videocall/internal/domain/repositories"
+)
+
+func TestCallEndsWhenLastParticipantLeaves(t *testing.T) {
+	cr := NewCallRepository()
+	start := time.Now()
+
+	_ = cr.JoinCall("room", entity.CallParticipant{SessionID: "a", UserID: "alice", JoinedAt: start})
+	_ = cr.JoinCall("room", entity.CallParticipant{SessionID: "b", UserID: "bob", JoinedAt: start.Add(time.Minute)})
+	// rejoin of a recorded session is not another participant
+	_ = cr.JoinCall("room", entity.CallParticipant{SessionID: "b", UserID: "bob", JoinedAt: start.Add(2 * time.Minute)})
+
+	_ = cr.LeaveCall("room", "a", start.Add(5*time.Minute))
+	calls, _ := cr.ListCalls(repositories.CallFilter{Limit: 10})
+	if len(calls) != 1 || !calls[0].Active() || len(calls[0].Participants) != 2 {
+		t.Fatalf("after the first leave calls are %+v, want one active call of 2 participants", calls)
+	}
+
+	_ = cr.LeaveCall("room", "b", start.Add(10*time.Minute))
+	calls, _ = cr.ListCalls(repositories.CallFilter{Limit: 10})
+	if calls[0].Active() || calls[0].Duration != 10*time.Minute {
+		t.Fatalf("call after the last leave is %+v, want ended after 10 minutes", calls[0])
+	}
+
+	// next join opens another call
+	_ = cr.JoinCall("room", entity.CallParticipant{SessionID: "c", UserID: "alice", JoinedAt: start.Add(time.Hour)})
+	calls, _ = cr.ListCalls(repositories.CallFilter{RoomID: "room", Limit: 10})
+	if len(calls) != 2 || !calls[0].Active() {
+		t.Fatalf("calls after rejoin are %+v, want a new active call first", calls)
+	}
+}
+
+func TestCloseStaleCalls(t *testing.T) {
+	cr := NewCallRepository()
+	started := time.Now()
+	before := started.Add(-time.Hour)
+
+	// room "crashed" lost all its participants, room "alive" keeps a live one on another instance
+	_ = cr.JoinCall("crashed", entity.CallParticipant{SessionID: "a", JoinedAt: before})
+	_ = cr.JoinCall("alive", entity.CallParticipant{SessionID: "b", JoinedAt: before})
+	_ = cr.JoinCall("alive", entity.CallParticipant{SessionID: "c", JoinedAt: before})
+	// joined after the instance started, its join may be newer than the snapshot of live sessions
+	_ = cr.JoinCall("fresh", entity.CallParticipant{SessionID: "d", JoinedAt: started.Add(time.Second)})
+
+	at := started.Add(time.Minute)
+	if err := cr.CloseStaleCalls(map[string]bool{"c": true}, started, at); err != nil {
+		t.Fatalf("CloseStaleCalls: %v", err)
+	}
+
+	active := map[string]bool{}
+	calls, _ := cr.ListCalls(repositories.CallFilter{Limit: 10})
+	for _, call := range calls {
+		active[call.RoomID] = call.Active()
+		if call.RoomID == "crashed" && !call.EndedAt.Equal(at) {
+			t.Fatalf("stale call ended at %v, want %v", call.EndedAt, at)
+		}
+	}
+
+	want := map[string]bool{"crashed": false, "alive": true, "fresh": true}
+	for roomID, wantActive := range want {
+		if active[roomID] != wantActive {
+			t.Errorf("call of room %s active = %v, want %v", roomID, active[roomID], wantActive)
+		}
+	}
+}
+
+func TestCallHistoryIsBounded(t *testing.T) {
+	cr := NewCallRepository()
+	start := time.Now()
+
+	// an active call is never forgotten
+	_ = cr.JoinCall("long", entity.CallParticipant{SessionID: "long", JoinedAt: start})
+
+	for i := 0; i < maxCalls+10; i++ {
+		_ = cr.JoinCall("room", entity.CallParticipant{SessionID: "s", JoinedAt: start})
+		_ = cr.LeaveCall("room", "s", start)
+	}
+
+	if len(cr.Calls) > maxCalls {
+		t.Fatalf("%d calls kept, want at most %d", len(cr.Calls), maxCalls)
+	}
+	if cr.Calls[0].RoomID != "long" || !cr.Calls[0].Active() {
+		t.Fatal("active call was pruned")
+	}
+}
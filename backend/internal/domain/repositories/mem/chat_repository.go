@@ -0,0 +1,59 @@
+package mem
+
+import (
+	"sort"
+	"sync"
+	"videocall/internal/domain/entity"
+)
+
+type ChatRepository struct {
+	mu       sync.RWMutex
+	lastID   int64
+	Messages map[string][]*entity.ChatMessage // room ID -> messages ordered by ID
+}
+
+func NewChatRepository() *ChatRepository {
+	return &ChatRepository{
+		Messages: make(map[string][]*entity.ChatMessage),
+	}
+}
+
+func (cr *ChatRepository) AddMessage(msg *entity.ChatMessage) error {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+
+	cr.lastID++
+	msg.ID = cr.lastID
+	cr.Messages[msg.RoomID] = append(cr.Messages[msg.RoomID], msg)
+
+	return nil
+}
+
+func (cr *ChatRepository) ListMessages(roomID string, beforeID int64, limit int) ([]*entity.ChatMessage, error) {
+	cr.mu.RLock()
+	defer cr.mu.RUnlock()
+
+	msgs := cr.Messages[roomID]
+
+	end := len(msgs)
+	if beforeID > 0 {
+		end = sort.Search(len(msgs), func(i int) bool {
+			return msgs[i].ID >= beforeID
+		})
+	}
+	start := max(end-limit, 0)
+
+	page := make([]*entity.ChatMessage, end-start)
+	copy(page, msgs[start:end])
+
+	return page, nil
+}
+
+func (cr *ChatRepository) DeleteRoomMessages(roomID string) error {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+
+	delete(cr.Messages, roomID)
+
+	return nil
+}
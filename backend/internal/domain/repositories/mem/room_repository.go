@@ -2,41 +2,133 @@ package mem
 
 import (
 	"log"
+	"slices"
 	"sync"
 	"time"
 	"videocall/internal/domain/entity"
+	"videocall/internal/domain/repositories"
 )
 
 type RoomRepository struct {
-	mu    sync.RWMutex
-	Rooms map[string]*entity.Room
+	mu      sync.RWMutex
+	Rooms   map[string]*entity.Room
+	Members map[string]map[string]time.Time // room ID -> user ID -> joined at
+	Bans    map[string]map[string]struct{}  // room ID -> kicked user IDs
 }
 
 func New() *RoomRepository {
 	rs := &RoomRepository{
-		Rooms: make(map[string]*entity.Room),
+		Rooms:   make(map[string]*entity.Room),
+		Members: make(map[string]map[string]time.Time),
+		Bans:    make(map[string]map[string]struct{}),
 	}
 
 	return rs
 }
 
-func (rs *RoomRepository) AddRoom(roomID, creatorUserID string) {
+func (rs *RoomRepository) AddRoom(room *entity.Room) {
 	rs.mu.Lock()
-	rs.Rooms[roomID] = &entity.Room{
-		CreatedAt:     time.Now(),
-		UpdatedAt:     time.Now(),
-		CreatorUserID: creatorUserID,
+	defer rs.mu.Unlock()
+
+	r := copyRoom(room)
+	r.CreatedAt = time.Now()
+	r.UpdatedAt = time.Now()
+	rs.Rooms[room.ID] = r
+}
+
+// copyRoom keeps stored rooms apart from the ones handed out to callers
+func copyRoom(room *entity.Room) *entity.Room {
+	r := *room
+	if room.Schedule != nil {
+		schedule := *room.Schedule
+		schedule.Invitees = slices.Clone(room.Schedule.Invitees)
+		if rec := room.Schedule.Recurrence; rec != nil {
+			recurrence := *rec
+			recurrence.ByDay = slices.Clone(rec.ByDay)
+			recurrence.Exceptions = slices.Clone(rec.Exceptions)
+			schedule.Recurrence = &recurrence
+		}
+		r.Schedule = &schedule
 	}
-	rs.mu.Unlock()
+
+	return &r
 }
 
+// GetRoom returns a copy, changes are saved with UpdateRoom
 func (rs *RoomRepository) GetRoom(roomID string) (*entity.Room, bool) {
 	rs.mu.RLock()
 	defer rs.mu.RUnlock()
 
 	r, ok := rs.Rooms[roomID]
+	if !ok {
+		return nil, false
+	}
+
+	return copyRoom(r), true
+}
+
+func (rs *RoomRepository) UpdateRoom(room *entity.Room) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	r, ok := rs.Rooms[room.ID]
+	if !ok {
+		return
+	}
+
+	r.Title = room.Title
+	r.Capacity = room.Capacity
+	r.Lobby = room.Lobby
+	r.Locked = room.Locked
+	r.PasscodeHash = room.PasscodeHash
+	r.Schedule = copyRoom(room).Schedule
+}
+
+func (rs *RoomRepository) AddMember(roomID, userID string) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	if _, ok := rs.Rooms[roomID]; !ok {
+		return
+	}
+
+	members, ok := rs.Members[roomID]
+	if !ok {
+		members = make(map[string]time.Time)
+		rs.Members[roomID] = members
+	}
+	if _, ok := members[userID]; !ok {
+		members[userID] = time.Now()
+	}
+}
+
+func (rs *RoomRepository) IsMember(roomID, userID string) bool {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+
+	_, ok := rs.Members[roomID][userID]
+
+	return ok
+}
+
+func (rs *RoomRepository) ListRooms(userID string) []*entity.Room {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
 
-	return r, ok
+	var rooms []*entity.Room
+	for roomID, r := range rs.Rooms {
+		_, joined := rs.Members[roomID][userID]
+		invited := r.Schedule != nil && slices.Contains(r.Schedule.Invitees, userID)
+		if r.CreatorUserID == userID || joined || invited {
+			rooms = append(rooms, copyRoom(r))
+		}
+	}
+
+	slices.SortFunc(rooms, func(a, b *entity.Room) int {
+		return b.UpdatedAt.Compare(a.UpdatedAt)
+	})
+
+	return rooms
 }
 
 func (rs *RoomRepository) RefreshRoom(roomID string) {
@@ -51,14 +143,120 @@ func (rs *RoomRepository) RefreshRoom(roomID string) {
 	rs.Rooms[roomID].UpdatedAt = time.Now()
 }
 
-func (rs *RoomRepository) CleanRooms(ttl time.Duration) {
+func (rs *RoomRepository) BanUser(roomID, userID string) {
 	rs.mu.Lock()
 	defer rs.mu.Unlock()
 
+	if _, ok := rs.Rooms[roomID]; !ok {
+		return
+	}
+
+	bans, ok := rs.Bans[roomID]
+	if !ok {
+		bans = make(map[string]struct{})
+		rs.Bans[roomID] = bans
+	}
+	bans[userID] = struct{}{}
+}
+
+func (rs *RoomRepository) IsBanned(roomID, userID string) bool {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+
+	_, ok := rs.Bans[roomID][userID]
+
+	return ok
+}
+
+func (rs *RoomRepository) SetPasscode(roomID, passcodeHash string) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	if room, ok := rs.Rooms[roomID]; ok {
+		room.PasscodeHash = passcodeHash
+	}
+}
+
+func (rs *RoomRepository) SetLocked(roomID string, locked bool) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	if room, ok := rs.Rooms[roomID]; ok {
+		room.Locked = locked
+	}
+}
+
+func (rs *RoomRepository) GetRoomBySlug(slug string) (*entity.Room, bool) {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+
+	for _, room := range rs.Rooms {
+		if room.Slug == slug {
+			return copyRoom(room), true
+		}
+	}
+
+	return nil, false
+}
+
+func (rs *RoomRepository) SetSlug(roomID, slug string) error {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	if slug != "" {
+		for id, room := range rs.Rooms {
+			if room.Slug == slug && id != roomID {
+				return repositories.ErrSlugTaken
+			}
+		}
+	}
+
+	if room, ok := rs.Rooms[roomID]; ok {
+		room.Slug = slug
+	}
+
+	return nil
+}
+
+func (rs *RoomRepository) SetOwner(roomID, userID string) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	if room, ok := rs.Rooms[roomID]; ok {
+		room.CreatorUserID = userID
+	}
+}
+
+func (rs *RoomRepository) DeleteRoom(roomID string) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	delete(rs.Rooms, roomID)
+	delete(rs.Members, roomID)
+	delete(rs.Bans, roomID)
+}
+
+func (rs *RoomRepository) CleanRooms(ttl time.Duration) []string {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	var deleted []string
 	for roomID, room := range rs.Rooms {
+		// scheduled meeting is kept as if it was last active at its end, endless series for good
+		if room.Schedule != nil && (room.Schedule.Endless() || room.Schedule.EndsAt().Add(ttl).After(time.Now())) {
+			continue
+		}
+		if room.Slug != "" {
+			continue
+		}
 		if room.UpdatedAt.Add(ttl).Before(time.Now()) {
 			delete(rs.Rooms, roomID)
+			delete(rs.Members, roomID)
+			delete(rs.Bans, roomID)
+			deleted = append(deleted, roomID)
 			log.Printf("autoclean: delete empty room %s", roomID)
 		}
 	}
+
+	return deleted
 }
@@ -0,0 +1,59 @@
+package mem
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const ticketCleanInterval = 60 * time.Second
+
+type SignalTicketRepository struct {
+	mu       sync.Mutex
+	Redeemed map[string]time.Time // nonce -> ticket expiry
+}
+
+func NewSignalTicketRepository(ctx context.Context) *SignalTicketRepository {
+	tr := &SignalTicketRepository{
+		Redeemed: make(map[string]time.Time),
+	}
+
+	tr.handleExpiredTickets(ctx)
+
+	return tr
+}
+
+func (t *SignalTicketRepository) MarkRedeemed(nonce string, expiry time.Time) (bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.Redeemed[nonce]; ok {
+		return false, nil
+	}
+	t.Redeemed[nonce] = expiry
+
+	return true, nil
+}
+
+// handleExpiredTickets forgets redeemed tickets once they expire and are refused anyway
+func (t *SignalTicketRepository) handleExpiredTickets(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(ticketCleanInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				t.mu.Lock()
+				for nonce, expiry := range t.Redeemed {
+					if expiry.Before(time.Now()) {
+						delete(t.Redeemed, nonce)
+					}
+				}
+				t.mu.Unlock()
+			}
+		}
+	}()
+}
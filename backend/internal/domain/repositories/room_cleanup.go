@@ -7,7 +7,7 @@ import (
 	"videocall/internal/infrastructure/config"
 )
 
-func HandleObsoleteRooms(ctx context.Context, rs RoomRepositoryInterface, conf config.RoomConfig) {
+func HandleObsoleteRooms(ctx context.Context, rs RoomRepositoryInterface, chats ChatRepositoryInterface, conf config.RoomConfig) {
 	go func() {
 		ticker := time.NewTicker(conf.CleanInterval)
 		defer ticker.Stop()
@@ -18,7 +18,11 @@ func HandleObsoleteRooms(ctx context.Context, rs RoomRepositoryInterface, conf c
 				return
 			case <-ticker.C:
 				log.Printf("dispatched room clean up task")
-				rs.CleanRooms(conf.TTL)
+				for _, roomID := range rs.CleanRooms(conf.TTL) {
+					if err := chats.DeleteRoomMessages(roomID); err != nil {
+						log.Printf("autoclean: failed to delete chat history of room %s: %v", roomID, err)
+					}
+				}
 			}
 		}
 	}()
@@ -0,0 +1,324 @@
+package repositories
+
+import (
+	"slices"
+	"sync"
+	"time"
+	"videocall/internal/infrastructure/messaging"
+
+	"github.com/google/uuid"
+)
+
+// session is a room slot of a signaling client. It outlives the websocket for a grace period,
+// messages addressed to a detached session are kept in backlog until client resumes
+type session struct {
+	ID       string
+	UserID   string
+	Username string
+	JoinedAt time.Time
+	client   *messaging.Client
+	backlog  [][]byte
+	expiry   *time.Timer
+	mu       sync.Mutex
+}
+
+func newSession(c *messaging.Client) *session {
+	c.SessionID = uuid.NewString()
+
+	return &session{
+		ID:       c.SessionID,
+		UserID:   c.UserID,
+		Username: c.Username,
+		JoinedAt: time.Now(),
+		client:   c,
+	}
+}
+
+func (s *session) deliver(msg []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.client != nil {
+		s.client.Send(msg)
+		return
+	}
+
+	if len(s.backlog) >= messaging.BufferSize {
+		s.backlog = s.backlog[1:]
+	}
+	s.backlog = append(s.backlog, msg)
+}
+
+func (s *session) attach(c *messaging.Client) (backlog [][]byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.expiry != nil {
+		s.expiry.Stop()
+		s.expiry = nil
+	}
+
+	if s.client != nil {
+		// stale connection the server has not noticed yet
+		s.client.Close()
+	}
+
+	c.SessionID = s.ID
+	s.client = c
+	backlog, s.backlog = s.backlog, nil
+
+	return backlog
+}
+
+func (s *session) detach(c *messaging.Client, grace time.Duration, onExpire func()) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.client != c {
+		return false
+	}
+	s.client = nil
+
+	if grace > 0 {
+		s.expiry = time.AfterFunc(grace, onExpire)
+	}
+
+	return true
+}
+
+func (s *session) stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.expiry != nil {
+		s.expiry.Stop()
+		s.expiry = nil
+	}
+}
+
+// evict unbinds session from its client for good and returns the client, if any, so that caller closes it
+func (s *session) evict() *messaging.Client {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.expiry != nil {
+		s.expiry.Stop()
+		s.expiry = nil
+	}
+
+	c := s.client
+	s.client = nil
+	s.backlog = nil
+
+	return c
+}
+
+func (s *session) peer() messaging.Peer {
+	return messaging.Peer{UserID: s.UserID, Username: s.Username, SessionID: s.ID, JoinedAt: s.JoinedAt.UnixMilli()}
+}
+
+func (s *session) detached() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.client == nil
+}
+
+// RoomHub keeps signaling sessions of a single room. Every device of a user has its own session
+type RoomHub struct {
+	RoomID   string
+	Capacity int
+	sessions map[string]*session // key: session ID
+	mu       sync.RWMutex
+}
+
+func newRoomHub(roomID string, capacity int) *RoomHub {
+	return &RoomHub{
+		RoomID:   roomID,
+		Capacity: capacity,
+		sessions: make(map[string]*session),
+	}
+}
+
+// setCapacity applies capacity changed by the host to the live room
+func (h *RoomHub) setCapacity(capacity int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.Capacity = capacity
+}
+
+// attach puts client into the hub. Client presenting ID of its own session resumes it and gets the backlog.
+// occupied is number of room slots taken on other instances
+func (h *RoomHub) attach(c *messaging.Client, sessionID string, occupied int) (backlog [][]byte, resumed bool, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if cur, ok := h.sessions[sessionID]; ok && cur.UserID == c.UserID {
+		return cur.attach(c), true, nil
+	}
+
+	if h.Capacity > 0 && len(h.sessions)+occupied >= h.Capacity {
+		return nil, false, ErrRoomFull
+	}
+
+	sess := newSession(c)
+	h.sessions[sess.ID] = sess
+
+	return nil, false, nil
+}
+
+// detach unbinds client from its session, session is kept until grace period expires
+func (h *RoomHub) detach(c *messaging.Client, grace time.Duration, onExpire func(*session)) (*session, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	sess, ok := h.sessions[c.SessionID]
+	if !ok {
+		return nil, false
+	}
+
+	if !sess.detach(c, grace, func() { onExpire(sess) }) {
+		return nil, false
+	}
+
+	return sess, true
+}
+
+// drop deletes detached session and reports whether the hub became empty
+func (h *RoomHub) drop(sess *session) (removed bool, empty bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if cur, ok := h.sessions[sess.ID]; ok && cur == sess && sess.detached() {
+		delete(h.sessions, sess.ID)
+		removed = true
+	}
+
+	return removed, len(h.sessions) == 0
+}
+
+// evict removes sessions of the user, or all sessions when userID is empty, without grace period
+func (h *RoomHub) evict(userID string) (evicted []*session, empty bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for id, sess := range h.sessions {
+		if userID == "" || sess.UserID == userID {
+			delete(h.sessions, id)
+			evicted = append(evicted, sess)
+		}
+	}
+
+	return evicted, len(h.sessions) == 0
+}
+
+// Broadcast delivers message to every session in the room except the given one
+func (h *RoomHub) Broadcast(exceptSessionID string, msg []byte) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for id, sess := range h.sessions {
+		if id != exceptSessionID {
+			sess.deliver(msg)
+		}
+	}
+}
+
+// SendToUser delivers message to all devices of the user
+func (h *RoomHub) SendToUser(userID string, msg []byte) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	found := false
+	for _, sess := range h.sessions {
+		if sess.UserID == userID {
+			sess.deliver(msg)
+			found = true
+		}
+	}
+
+	return found
+}
+
+// SendToSession delivers message to a single device. Non-empty userID must own the session
+func (h *RoomHub) SendToSession(sessionID, userID string, msg []byte) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	sess, ok := h.sessions[sessionID]
+	if !ok || (userID != "" && sess.UserID != userID) {
+		return false
+	}
+	sess.deliver(msg)
+
+	return true
+}
+
+// Roster returns snapshot of the room for the given client
+func (h *RoomHub) Roster(c *messaging.Client) messaging.RosterPayload {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	peers := make([]messaging.Peer, 0, len(h.sessions))
+	for id, sess := range h.sessions {
+		if id != c.SessionID {
+			peers = append(peers, sess.peer())
+		}
+	}
+
+	return messaging.RosterPayload{
+		RoomID:   h.RoomID,
+		Capacity: h.Capacity,
+		Peers:    peers,
+	}
+}
+
+// Peer returns the session of the room
+func (h *RoomHub) Peer(sessionID string) (messaging.Peer, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	sess, ok := h.sessions[sessionID]
+	if !ok {
+		return messaging.Peer{}, false
+	}
+
+	return sess.peer(), true
+}
+
+// Peers returns all sessions of the room
+func (h *RoomHub) Peers() []messaging.Peer {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	peers := make([]messaging.Peer, 0, len(h.sessions))
+	for _, sess := range h.sessions {
+		peers = append(peers, sess.peer())
+	}
+
+	return peers
+}
+
+// Len returns number of sessions (devices) in the room
+func (h *RoomHub) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return len(h.sessions)
+}
+
+// UserIDs returns unique users present in the room
+func (h *RoomHub) UserIDs() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	ids := make([]string, 0, len(h.sessions))
+	for _, sess := range h.sessions {
+		if !slices.Contains(ids, sess.UserID) {
+			ids = append(ids, sess.UserID)
+		}
+	}
+
+	return ids
+}
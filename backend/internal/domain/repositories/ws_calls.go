@@ -0,0 +1,140 @@
+package repositories
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+	"videocall/internal/domain/entity"
+	"videocall/internal/infrastructure/messaging"
+)
+
+// callBacklogWarning is how many joins and leaves may wait to be written to call history before it is logged
+const callBacklogWarning = 1024
+
+// callEvent is a join (participant set) or leave of a local session, or a cleanup of calls left open (live set)
+type callEvent struct {
+	roomID      string
+	participant *entity.CallParticipant
+	sessionID   string
+	at          time.Time
+	// live is a snapshot of sessions connected to the cluster, participants not in it that joined before at have gone
+	live map[string]bool
+}
+
+// callQueue is an unbounded FIFO of call events. Queuing never blocks, so it can be done with r.mu held,
+// and never drops, so a call is not left open when storage is slow
+type callQueue struct {
+	mu     sync.Mutex
+	events []callEvent
+	notify chan struct{}
+}
+
+func newCallQueue() *callQueue {
+	return &callQueue{notify: make(chan struct{}, 1)}
+}
+
+func (q *callQueue) push(ev callEvent) {
+	q.mu.Lock()
+	q.events = append(q.events, ev)
+	backlog := len(q.events)
+	q.mu.Unlock()
+
+	if backlog%callBacklogWarning == 0 {
+		log.Printf("call history is %d events behind", backlog)
+	}
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (q *callQueue) drain() []callEvent {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	events := q.events
+	q.events = nil
+
+	return events
+}
+
+// recordJoin queues joining of a local session to call history. Every instance records its own sessions only
+func (r *Connections) recordJoin(roomID string, peer messaging.Peer) {
+	r.queueCallEvent(callEvent{
+		roomID: roomID,
+		participant: &entity.CallParticipant{
+			SessionID: peer.SessionID,
+			UserID:    peer.UserID,
+			Username:  peer.Username,
+			JoinedAt:  time.UnixMilli(peer.JoinedAt),
+		},
+	})
+}
+
+func (r *Connections) recordLeave(roomID, sessionID string) {
+	r.queueCallEvent(callEvent{roomID: roomID, sessionID: sessionID, at: time.Now()})
+}
+
+// queueCallEvent keeps storage writes out of r.mu, events are written in order by recordCalls
+func (r *Connections) queueCallEvent(ev callEvent) {
+	r.callEvents.push(ev)
+}
+
+func (r *Connections) recordCalls(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.callEvents.notify:
+			for _, ev := range r.callEvents.drain() {
+				r.recordCall(ev)
+			}
+		}
+	}
+}
+
+func (r *Connections) recordCall(ev callEvent) {
+	var err error
+	switch {
+	case ev.live != nil:
+		err = r.calls.CloseStaleCalls(ev.live, ev.at, time.Now())
+	case ev.participant != nil:
+		err = r.calls.JoinCall(ev.roomID, *ev.participant)
+	default:
+		err = r.calls.LeaveCall(ev.roomID, ev.sessionID, ev.at)
+	}
+	if err != nil {
+		log.Printf("failed to record call history of room %q: %v", ev.roomID, err)
+	}
+}
+
+// closeStaleCalls ends calls left open by an instance that stopped without recording leaves, e.g. after a crash.
+// It waits NodeTimeout for heartbeats of other instances, so that their participants are known and kept.
+// Sessions that joined after this instance started are never touched
+func (r *Connections) closeStaleCalls(ctx context.Context, started time.Time) {
+	select {
+	case <-ctx.Done():
+		return
+	case <-time.After(r.busCfg.NodeTimeout):
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	live := make(map[string]bool)
+	for _, hub := range r.rooms {
+		for _, p := range hub.Peers() {
+			live[p.SessionID] = true
+		}
+	}
+	for _, peers := range r.remote {
+		for sessionID := range peers {
+			live[sessionID] = true
+		}
+	}
+
+	// queued with r.mu held, so joins before the snapshot are written before the cleanup
+	r.queueCallEvent(callEvent{live: live, at: started})
+}
@@ -0,0 +1,57 @@
+package repositories
+
+import (
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+	"videocall/internal/domain/entity"
+	"videocall/internal/infrastructure/messaging"
+)
+
+// chat stores message in room history and delivers it to every participant, the sender included,
+// so all of them see the same ID and time. Chat is always room-wide
+func (r *Connections) chat(sender *messaging.Client, hub *RoomHub, env *messaging.Envelope) {
+	var payload messaging.ChatPayload
+	if err := json.Unmarshal(env.Payload, &payload); err != nil {
+		sender.Send(messaging.ErrorFrame(messaging.ErrMalformedMessage, env.Type))
+		return
+	}
+
+	text := strings.TrimSpace(payload.Text)
+	if text == "" || utf8.RuneCountInString(text) > r.cfg.ChatMaxLength {
+		sender.Send(messaging.ErrorFrame(messaging.ErrInvalidChat, env.Type))
+		return
+	}
+
+	msg := &entity.ChatMessage{
+		RoomID:    hub.RoomID,
+		UserID:    sender.UserID,
+		Username:  sender.Username,
+		Text:      text,
+		CreatedAt: time.Now(),
+	}
+	if err := r.chats.AddMessage(msg); err != nil {
+		log.Printf("failed to store chat message from %s in room %s: %v", sender.UserID, hub.RoomID, err)
+		sender.Send(messaging.ErrorFrame(messaging.ErrChatNotSaved, env.Type))
+		return
+	}
+
+	env.To = ""
+	env.ToSession = ""
+	env.Payload, _ = json.Marshal(messaging.ChatPayload{
+		ID:     msg.ID,
+		Text:   msg.Text,
+		SentAt: msg.CreatedAt.UnixMilli(),
+	})
+
+	frame, err := env.Encode()
+	if err != nil {
+		log.Printf("failed to encode chat message from %s: %v", sender.UserID, err)
+		return
+	}
+
+	hub.Broadcast("", frame)
+	r.relayRemote(hub, sender, env, frame)
+}
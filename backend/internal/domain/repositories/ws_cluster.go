@@ -0,0 +1,273 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+	"videocall/internal/infrastructure/messaging"
+)
+
+// Cluster event kinds exchanged over the bus
+const (
+	clusterRelay     = "relay"
+	clusterJoin      = "join"
+	clusterLeave     = "leave"
+	clusterHeartbeat = "heartbeat"
+	clusterKick      = "kick"
+	clusterEnd       = "end"
+	clusterKnock     = "knock"
+	clusterLobby     = "lobby"
+	clusterHand      = "hand"
+)
+
+// clusterEvent is published to the bus so that instances know about each other's participants
+// and can deliver messages to clients connected elsewhere
+type clusterEvent struct {
+	Node          string                      `json:"node"`
+	Kind          string                      `json:"kind"`
+	RoomID        string                      `json:"room,omitempty"`
+	ExceptSession string                      `json:"except_session,omitempty"`
+	ToUser        string                      `json:"to_user,omitempty"`
+	ToSession     string                      `json:"to_session,omitempty"`
+	Peer          *messaging.Peer             `json:"peer,omitempty"`
+	Rooms         map[string][]messaging.Peer `json:"rooms,omitempty"`
+	Frame         json.RawMessage             `json:"frame,omitempty"`
+	Reason        string                      `json:"reason,omitempty"`
+	Lobby         *LobbyRequest               `json:"lobby,omitempty"`
+	Hand          *messaging.Hand             `json:"hand,omitempty"`
+	Raised        bool                        `json:"raised,omitempty"`
+}
+
+// remotePeer is a device connected to another instance
+type remotePeer struct {
+	messaging.Peer
+	node string
+}
+
+func (r *Connections) publish(ev clusterEvent) {
+	ev.Node = r.nodeID
+
+	data, err := json.Marshal(ev)
+	if err != nil {
+		log.Printf("failed to encode %s cluster event: %v", ev.Kind, err)
+		return
+	}
+
+	if err := r.bus.Publish(data); err != nil {
+		log.Printf("failed to publish %s cluster event: %v", ev.Kind, err)
+	}
+}
+
+// relayRemote forwards client message to other instances when the room has participants there
+func (r *Connections) relayRemote(hub *RoomHub, sender *messaging.Client, env *messaging.Envelope, msg []byte) (delivered bool) {
+	r.mu.RLock()
+	remote := r.remote[hub.RoomID]
+	switch {
+	case env.ToSession != "":
+		p, ok := remote[env.ToSession]
+		delivered = ok && (env.To == "" || p.UserID == env.To)
+	case env.To != "":
+		for _, p := range remote {
+			if p.UserID == env.To {
+				delivered = true
+				break
+			}
+		}
+	default:
+		delivered = len(remote) > 0
+	}
+	r.mu.RUnlock()
+
+	if delivered {
+		r.publish(clusterEvent{
+			Kind:          clusterRelay,
+			RoomID:        hub.RoomID,
+			ExceptSession: sender.SessionID,
+			ToUser:        env.To,
+			ToSession:     env.ToSession,
+			Frame:         msg,
+		})
+	}
+
+	return delivered
+}
+
+func (r *Connections) onClusterEvent(data []byte) {
+	var ev clusterEvent
+	if err := json.Unmarshal(data, &ev); err != nil {
+		log.Printf("malformed cluster event: %v", err)
+		return
+	}
+
+	if ev.Node == r.nodeID {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nodes[ev.Node] = time.Now()
+
+	switch ev.Kind {
+	case clusterRelay:
+		hub, ok := r.rooms[ev.RoomID]
+		if !ok {
+			return
+		}
+		switch {
+		case ev.ToSession != "":
+			hub.SendToSession(ev.ToSession, ev.ToUser, ev.Frame)
+		case ev.ToUser != "":
+			hub.SendToUser(ev.ToUser, ev.Frame)
+		default:
+			hub.Broadcast(ev.ExceptSession, ev.Frame)
+		}
+	case clusterJoin:
+		if ev.Peer != nil {
+			r.addRemote(ev.RoomID, remotePeer{Peer: *ev.Peer, node: ev.Node})
+		}
+	case clusterLeave:
+		if ev.Peer != nil {
+			r.removeRemote(ev.RoomID, ev.Peer.SessionID)
+		}
+	case clusterHeartbeat:
+		r.syncNode(ev.Node, ev.Rooms)
+	case clusterKick:
+		r.kickLocal(ev.RoomID, ev.ToUser, ev.Reason)
+	case clusterEnd:
+		r.endLocal(ev.RoomID, ev.Reason)
+	case clusterKnock, clusterLobby:
+		r.onLobbyEvent(ev.Node, ev.Lobby)
+	case clusterHand:
+		if ev.Hand != nil {
+			r.setHand(ev.RoomID, *ev.Hand, ev.Raised)
+		}
+	}
+}
+
+// addRemote must be called with r.mu held
+func (r *Connections) addRemote(roomID string, p remotePeer) {
+	peers, ok := r.remote[roomID]
+	if !ok {
+		peers = make(map[string]remotePeer)
+		r.remote[roomID] = peers
+	}
+
+	if _, ok := peers[p.SessionID]; ok {
+		return
+	}
+	peers[p.SessionID] = p
+
+	if hub, ok := r.rooms[roomID]; ok {
+		hub.Broadcast("", messaging.Frame(messaging.TypePeerJoined, p.Peer))
+	}
+}
+
+// removeRemote must be called with r.mu held
+func (r *Connections) removeRemote(roomID, sessionID string) {
+	p, ok := r.remote[roomID][sessionID]
+	if !ok {
+		return
+	}
+
+	delete(r.remote[roomID], sessionID)
+	if len(r.remote[roomID]) == 0 {
+		delete(r.remote, roomID)
+	}
+
+	if hub, ok := r.rooms[roomID]; ok {
+		hub.Broadcast("", messaging.Frame(messaging.TypePeerLeft, p.Peer))
+	}
+
+	r.clearHand(roomID, p.UserID)
+}
+
+// syncNode replaces known participants of the node with its snapshot. Must be called with r.mu held
+func (r *Connections) syncNode(node string, rooms map[string][]messaging.Peer) {
+	for roomID, peers := range r.remote {
+		for sessionID, p := range peers {
+			if p.node != node {
+				continue
+			}
+
+			found := false
+			for _, cur := range rooms[roomID] {
+				if cur.SessionID == sessionID {
+					found = true
+					break
+				}
+			}
+
+			if !found {
+				r.removeRemote(roomID, sessionID)
+			}
+		}
+	}
+
+	for roomID, peers := range rooms {
+		for _, p := range peers {
+			r.addRemote(roomID, remotePeer{Peer: p, node: node})
+		}
+	}
+}
+
+// forgetNode drops participants of the node that stopped sending heartbeats. Must be called with r.mu held
+func (r *Connections) forgetNode(node string) {
+	log.Printf("cluster node %s timed out, dropping its participants", node)
+
+	delete(r.nodes, node)
+
+	// the instance cannot record leaves of its participants any more. Every instance does it, leaving twice is a no-op
+	for roomID, peers := range r.remote {
+		for sessionID, p := range peers {
+			if p.node == node {
+				r.recordLeave(roomID, sessionID)
+			}
+		}
+	}
+
+	r.syncNode(node, nil)
+}
+
+// heartbeat periodically announces local participants and expires silent instances
+func (r *Connections) heartbeat(ctx context.Context) {
+	ticker := time.NewTicker(r.busCfg.HeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.mu.Lock()
+			rooms := make(map[string][]messaging.Peer, len(r.rooms))
+			for roomID, hub := range r.rooms {
+				rooms[roomID] = hub.Peers()
+			}
+
+			for node, seen := range r.nodes {
+				if time.Since(seen) > r.busCfg.NodeTimeout {
+					r.forgetNode(node)
+				}
+			}
+			r.expireLobby()
+			r.mu.Unlock()
+
+			r.publish(clusterEvent{
+				Kind:  clusterHeartbeat,
+				Rooms: rooms,
+			})
+		}
+	}
+}
+
+// remoteRoster returns participants of the room connected to other instances. Must be called with r.mu held
+func (r *Connections) remoteRoster(roomID string) []messaging.Peer {
+	peers := make([]messaging.Peer, 0, len(r.remote[roomID]))
+	for _, p := range r.remote[roomID] {
+		peers = append(peers, p.Peer)
+	}
+
+	return peers
+}
@@ -0,0 +1,432 @@
+package repositories_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"videocall/internal/domain/entity"
+	"videocall/internal/domain/repositories"
+	"videocall/internal/domain/repositories/mem"
+	"videocall/internal/infrastructure/auth"
+	"videocall/internal/infrastructure/bus"
+	"videocall/internal/infrastructure/config"
+	"videocall/internal/infrastructure/messaging"
+
+	"github.com/gorilla/websocket"
+)
+
+// mesh links are dialed once per retry interval, the first cross-node message may wait for it
+const clusterWait = 5 * time.Second
+
+const testRoom = "room"
+
+// testNode is a backend instance: its own bus endpoint, Connections and signaling server
+type testNode struct {
+	conns  *repositories.Connections
+	calls  *mem.CallRepository
+	bus    bus.Bus
+	server *httptest.Server
+	cfg    *config.Config
+	ctx    context.Context
+}
+
+// newCluster starts two instances linked with mesh bus that share room and call storage like they share database
+func newCluster(t *testing.T) (*testNode, *testNode, *mem.RoomRepository) {
+	t.Helper()
+
+	return newClusterWithCalls(t, mem.NewCallRepository())
+}
+
+func newClusterWithCalls(t *testing.T, calls *mem.CallRepository) (*testNode, *testNode, *mem.RoomRepository) {
+	t.Helper()
+
+	rooms := mem.New()
+	rooms.AddRoom(&entity.Room{ID: testRoom, CreatorUserID: "host"})
+
+	addrA, addrB := freeAddr(t), freeAddr(t)
+
+	return newTestNode(t, "a", addrA, addrB, rooms, calls), newTestNode(t, "b", addrB, addrA, rooms, calls), rooms
+}
+
+func newTestNode(t *testing.T, nodeID, listen, peer string, rooms *mem.RoomRepository, calls *mem.CallRepository) *testNode {
+	t.Helper()
+
+	cfg := &config.Config{
+		Signaling: config.Signaling{
+			PingInterval:     time.Second,
+			PongTimeout:      5 * time.Second,
+			WriteTimeout:     time.Second,
+			SendBuffer:       64,
+			MaxMessageSize:   65536,
+			RateLimitStrikes: 100,
+			ChatMaxLength:    2000,
+		},
+		Bus: config.Bus{
+			Type:              bus.TypeMesh,
+			NodeID:            nodeID,
+			ListenAddr:        listen,
+			Peers:             []string{peer},
+			Secret:            "secret",
+			HeartbeatInterval: 100 * time.Millisecond,
+			NodeTimeout:       500 * time.Millisecond,
+		},
+		RoomConfig: config.RoomConfig{
+			LobbyTimeout: time.Minute,
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	b, err := bus.New(ctx, cfg.Bus)
+	if err != nil {
+		t.Fatalf("bus: %v", err)
+	}
+
+	n := &testNode{
+		conns: repositories.NewConnections(ctx, cfg, b, rooms, mem.NewChatRepository(), calls),
+		calls: calls,
+		bus:   b,
+		cfg:   cfg,
+		ctx:   ctx,
+	}
+	n.server = httptest.NewServer(http.HandlerFunc(n.serve))
+
+	t.Cleanup(func() {
+		cancel()
+		n.server.Close()
+		_ = b.Close()
+	})
+
+	return n
+}
+
+// serve runs signaling connection the same way the websocket handler does, identity comes from query
+func (n *testNode) serve(w http.ResponseWriter, r *http.Request) {
+	user := r.URL.Query().Get("user")
+	claims := &auth.Claims{UserID: user, Username: user, RoomID: r.URL.Query().Get("room")}
+
+	client, err := messaging.NewClient(w, r, claims, nil, n.cfg.Signaling)
+	if err != nil {
+		return
+	}
+
+	hub, err := n.conns.AddClient(client, 8, "")
+	if err != nil {
+		client.CloseWithReason(messaging.CloseRoomFull, err.Error())
+		return
+	}
+
+	read := make(chan *messaging.Envelope, messaging.BufferSize)
+	done := make(chan struct{}, 2)
+
+	go n.conns.Publisher(n.ctx, client, hub, read, done)
+	go client.WritePump(n.ctx, done)
+	client.ReadPump(n.ctx, read)
+
+	<-done
+
+	n.conns.RemoveClient(client)
+}
+
+type testPeer struct {
+	t    *testing.T
+	conn *websocket.Conn
+}
+
+// connect joins the room on the node and waits for the roster
+func (n *testNode) connect(t *testing.T, user string) *testPeer {
+	t.Helper()
+
+	url := "ws" + strings.TrimPrefix(n.server.URL, "http") + "/?room=" + testRoom + "&user=" + user
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial as %s: %v", user, err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	p := &testPeer{t: t, conn: conn}
+	p.expect(messaging.TypeRoster)
+
+	return p
+}
+
+func (p *testPeer) send(env messaging.Envelope) {
+	p.t.Helper()
+
+	if err := p.conn.WriteJSON(env); err != nil {
+		p.t.Fatalf("send %s: %v", env.Type, err)
+	}
+}
+
+// expect skips other messages until one of msgType arrives
+func (p *testPeer) expect(msgType string) *messaging.Envelope {
+	p.t.Helper()
+
+	_ = p.conn.SetReadDeadline(time.Now().Add(clusterWait))
+	for {
+		_, data, err := p.conn.ReadMessage()
+		if err != nil {
+			p.t.Fatalf("waiting for %s: %v", msgType, err)
+		}
+
+		var env messaging.Envelope
+		if err := json.Unmarshal(data, &env); err != nil {
+			p.t.Fatalf("malformed message %s: %v", data, err)
+		}
+		if env.Type == msgType {
+			return &env
+		}
+	}
+}
+
+// expectPeer waits for presence event about the user
+func (p *testPeer) expectPeer(msgType, userID string) {
+	p.t.Helper()
+
+	for {
+		var peer messaging.Peer
+		if err := json.Unmarshal(p.expect(msgType).Payload, &peer); err != nil {
+			p.t.Fatalf("malformed %s payload: %v", msgType, err)
+		}
+		if peer.UserID == userID {
+			return
+		}
+	}
+}
+
+func (p *testPeer) expectClose(code int) {
+	p.t.Helper()
+
+	_ = p.conn.SetReadDeadline(time.Now().Add(clusterWait))
+	for {
+		_, _, err := p.conn.ReadMessage()
+		if err == nil {
+			continue
+		}
+
+		var closeErr *websocket.CloseError
+		if !errors.As(err, &closeErr) || closeErr.Code != code {
+			p.t.Fatalf("connection closed with %v, want code %d", err, code)
+		}
+		return
+	}
+}
+
+func TestClusterForward(t *testing.T) {
+	a, b, _ := newCluster(t)
+
+	alice := a.connect(t, "alice")
+	bob := b.connect(t, "bob")
+	alice.expectPeer(messaging.TypePeerJoined, "bob")
+
+	alice.send(messaging.Envelope{Type: messaging.TypeOffer, To: "bob", Payload: json.RawMessage(`{"sdp":"v=0"}`)})
+	offer := bob.expect(messaging.TypeOffer)
+	if offer.From != "alice" || string(offer.Payload) != `{"sdp":"v=0"}` {
+		t.Fatalf("bob received offer %+v", offer)
+	}
+
+	bob.send(messaging.Envelope{Type: messaging.TypeHello})
+	if hello := alice.expect(messaging.TypeHello); hello.From != "bob" {
+		t.Fatalf("alice received hello from %q", hello.From)
+	}
+
+	if size := a.conns.RoomSize(testRoom); size != 2 {
+		t.Fatalf("node a counts %d participants, want 2", size)
+	}
+}
+
+func TestClusterKick(t *testing.T) {
+	a, b, _ := newCluster(t)
+
+	host := a.connect(t, "host")
+	guest := b.connect(t, "guest")
+	host.expectPeer(messaging.TypePeerJoined, "guest")
+
+	host.send(messaging.Envelope{Type: messaging.TypeKick, To: "guest"})
+
+	guest.expectClose(messaging.CloseKicked)
+	host.expectPeer(messaging.TypePeerLeft, "guest")
+
+	for name, n := range map[string]*testNode{"a": a, "b": b} {
+		if !n.conns.Banned(testRoom, "guest") {
+			t.Fatalf("guest is not banned on node %s", name)
+		}
+	}
+}
+
+func TestClusterEnd(t *testing.T) {
+	a, b, rooms := newCluster(t)
+
+	host := a.connect(t, "host")
+	guest := b.connect(t, "guest")
+	host.expectPeer(messaging.TypePeerJoined, "guest")
+
+	host.send(messaging.Envelope{Type: messaging.TypeEndMeeting})
+
+	guest.expectClose(messaging.CloseRoomEnded)
+	host.expectClose(messaging.CloseRoomEnded)
+
+	if _, ok := rooms.GetRoom(testRoom); ok {
+		t.Fatal("ended room still exists")
+	}
+}
+
+// endMeeting has the host on node a end the meeting with a guest on node b
+func endMeeting(t *testing.T, a, b *testNode) {
+	t.Helper()
+
+	host := a.connect(t, "host")
+	guest := b.connect(t, "guest")
+	host.expectPeer(messaging.TypePeerJoined, "guest")
+
+	host.send(messaging.Envelope{Type: messaging.TypeEndMeeting})
+
+	guest.expectClose(messaging.CloseRoomEnded)
+	host.expectClose(messaging.CloseRoomEnded)
+}
+
+func TestEndKeepsRecurringSeries(t *testing.T) {
+	a, b, rooms := newCluster(t)
+
+	room, _ := rooms.GetRoom(testRoom)
+	room.Schedule = &entity.Schedule{
+		StartsAt:   time.Now(),
+		Duration:   time.Hour,
+		Recurrence: &entity.Recurrence{Freq: entity.FreqDaily, Interval: 1},
+	}
+	rooms.UpdateRoom(room)
+
+	endMeeting(t, a, b)
+
+	if _, ok := rooms.GetRoom(testRoom); !ok {
+		t.Fatal("ending a meeting deleted the recurring series")
+	}
+
+	// the next meeting of the series takes place in the same room
+	a.connect(t, "host")
+
+	a.conns.DeleteRoom(testRoom, "room deleted")
+	if _, ok := rooms.GetRoom(testRoom); ok {
+		t.Fatal("deleted series still exists")
+	}
+}
+
+func TestEndKeepsSlugRoom(t *testing.T) {
+	a, b, rooms := newCluster(t)
+
+	if err := rooms.SetSlug(testRoom, "standup"); err != nil {
+		t.Fatalf("SetSlug: %v", err)
+	}
+
+	endMeeting(t, a, b)
+
+	room, ok := rooms.GetRoomBySlug("standup")
+	if !ok || room.ID != testRoom {
+		t.Fatal("slug does not resolve to the room after the meeting ended")
+	}
+
+	a.connect(t, "host")
+}
+
+func TestClusterNodeExpiry(t *testing.T) {
+	a, b, _ := newCluster(t)
+
+	host := a.connect(t, "host")
+	b.connect(t, "guest")
+	host.expectPeer(messaging.TypePeerJoined, "guest")
+
+	// heartbeats keep the other node alive well past its timeout
+	time.Sleep(3 * a.cfg.Bus.NodeTimeout)
+	if size := a.conns.RoomSize(testRoom); size != 2 {
+		t.Fatalf("node a counts %d participants while b is alive, want 2", size)
+	}
+
+	// node b goes silent without announcing leaves
+	_ = b.bus.Close()
+
+	host.expectPeer(messaging.TypePeerLeft, "guest")
+	if size := a.conns.RoomSize(testRoom); size != 1 {
+		t.Fatalf("node a counts %d participants after b expired, want 1", size)
+	}
+}
+
+// waitCalls polls call history until cond holds, writes are asynchronous
+func waitCalls(t *testing.T, calls *mem.CallRepository, cond func([]*entity.Call) bool) []*entity.Call {
+	t.Helper()
+
+	deadline := time.Now().Add(clusterWait)
+	for {
+		list, err := calls.ListCalls(repositories.CallFilter{Limit: 10})
+		if err != nil {
+			t.Fatalf("ListCalls: %v", err)
+		}
+		if cond(list) {
+			return list
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("call history never reached expected state: %+v", list)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestClusterCallHistory(t *testing.T) {
+	a, b, _ := newCluster(t)
+
+	host := a.connect(t, "host")
+	guest := b.connect(t, "guest")
+	host.expectPeer(messaging.TypePeerJoined, "guest")
+
+	waitCalls(t, a.calls, func(calls []*entity.Call) bool {
+		return len(calls) == 1 && calls[0].Active() && len(calls[0].Participants) == 2
+	})
+
+	_ = guest.conn.Close()
+	host.expectPeer(messaging.TypePeerLeft, "guest")
+	_ = host.conn.Close()
+
+	waitCalls(t, a.calls, func(calls []*entity.Call) bool {
+		return len(calls) == 1 && !calls[0].Active()
+	})
+}
+
+func TestStaleCallsClosedOnStart(t *testing.T) {
+	calls := mem.NewCallRepository()
+	// left open by an instance that crashed before recording the leave
+	_ = calls.JoinCall(testRoom, entity.CallParticipant{SessionID: "crashed", UserID: "ghost", JoinedAt: time.Now().Add(-time.Hour)})
+
+	a, _, _ := newClusterWithCalls(t, calls)
+
+	list := waitCalls(t, calls, func(calls []*entity.Call) bool {
+		return len(calls) == 1 && !calls[0].Active()
+	})
+	if list[0].Participants[0].LeftAt.IsZero() {
+		t.Fatal("participant of the stale call was not marked as left")
+	}
+
+	// a meeting started after the cleanup opens a new call
+	a.connect(t, "host")
+	waitCalls(t, calls, func(calls []*entity.Call) bool {
+		return len(calls) == 2 && calls[0].Active()
+	})
+}
+
+func freeAddr(t *testing.T) string {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer l.Close()
+
+	return l.Addr().String()
+}
@@ -3,22 +3,70 @@ package repositories
 import (
 	"context"
 	"log"
+	"slices"
 	"sync"
+	"time"
+	"videocall/internal/infrastructure/bus"
+	"videocall/internal/infrastructure/config"
 	"videocall/internal/infrastructure/messaging"
+
+	"github.com/google/uuid"
 )
 
+// Connections keeps a signaling hub per room. Hub is created on first join and dropped when the last local client leaves.
+// Participants connected to other instances are learned from the bus and kept in remote
 type Connections struct {
-	WsClients map[string]*messaging.Client
-	mu        sync.RWMutex
+	cfg            config.Signaling
+	busCfg         config.Bus
+	nodeID         string
+	bus            bus.Bus
+	roomRepository RoomRepositoryInterface
+	chats          ChatRepositoryInterface
+	calls          CallRepositoryInterface
+	callEvents     *callQueue
+	rooms          map[string]*RoomHub
+	remote         map[string]map[string]remotePeer // room ID -> session ID -> peer
+	nodes          map[string]time.Time             // node ID -> last heard
+	lobby          map[string]*lobbyEntry           // request ID -> join request
+	lobbyTimeout   time.Duration
+	hands          map[string][]messaging.Hand // room ID -> raised-hand queue
+	mu             sync.RWMutex
 }
 
-func NewConnections() *Connections {
-	return &Connections{
-		WsClients: make(map[string]*messaging.Client),
+func NewConnections(ctx context.Context, cfg *config.Config, b bus.Bus, roomRepo RoomRepositoryInterface, chats ChatRepositoryInterface, calls CallRepositoryInterface) *Connections {
+	nodeID := cfg.Bus.NodeID
+	if nodeID == "" {
+		nodeID = uuid.NewString()
+	}
+
+	r := &Connections{
+		cfg:            cfg.Signaling,
+		busCfg:         cfg.Bus,
+		nodeID:         nodeID,
+		bus:            b,
+		roomRepository: roomRepo,
+		chats:          chats,
+		calls:          calls,
+		callEvents:     newCallQueue(),
+		rooms:          make(map[string]*RoomHub),
+		remote:         make(map[string]map[string]remotePeer),
+		nodes:          make(map[string]time.Time),
+		lobby:          make(map[string]*lobbyEntry),
+		lobbyTimeout:   cfg.RoomConfig.LobbyTimeout,
+		hands:          make(map[string][]messaging.Hand),
 	}
+
+	b.Subscribe(r.onClusterEvent)
+	go r.heartbeat(ctx)
+	go r.recordCalls(ctx)
+	go r.closeStaleCalls(ctx, time.Now())
+
+	log.Printf("signaling node %s started", nodeID)
+
+	return r
 }
 
-func (r *Connections) Publisher(ctx context.Context, sender *messaging.Client, read <-chan []byte, done chan<- struct{}) {
+func (r *Connections) Publisher(ctx context.Context, sender *messaging.Client, hub *RoomHub, read <-chan *messaging.Envelope, done chan<- struct{}) {
 	defer func() {
 		done <- struct{}{}
 	}()
@@ -26,36 +74,214 @@ func (r *Connections) Publisher(ctx context.Context, sender *messaging.Client, r
 		select {
 		case <-ctx.Done():
 			return
-		case msg, ok := <-read:
+		case env, ok := <-read:
 			if !ok {
 				return
 			}
-			r.mu.RLock()
-			for userID, c := range r.WsClients {
-				if userID != sender.UserID {
-					c.Send(msg)
-				}
-			}
-			r.mu.RUnlock()
+			r.route(sender, hub, env)
 		}
 	}
 }
 
-func (r *Connections) AddClient(c *messaging.Client, roomID string) {
+func (r *Connections) route(sender *messaging.Client, hub *RoomHub, env *messaging.Envelope) {
+	switch env.Type {
+	case messaging.TypeChat:
+		r.chat(sender, hub, env)
+	case messaging.TypeKick, messaging.TypeMuteRequest, messaging.TypeEndMeeting, messaging.TypeAdmit, messaging.TypeDeny:
+		r.moderate(sender, hub, env)
+	case messaging.TypeRaiseHand, messaging.TypeLowerHand:
+		r.hand(sender, hub, env)
+	case messaging.TypeReaction:
+		r.react(sender, hub, env)
+	default:
+		r.forward(sender, hub, env)
+	}
+}
+
+// forward delivers client message to its recipients as is
+func (r *Connections) forward(sender *messaging.Client, hub *RoomHub, env *messaging.Envelope) {
+	msg, err := env.Encode()
+	if err != nil {
+		log.Printf("failed to encode %s message from %s: %v", env.Type, sender.UserID, err)
+		return
+	}
+
+	delivered := true
+	switch {
+	case env.ToSession != "":
+		delivered = hub.SendToSession(env.ToSession, env.To, msg) || r.relayRemote(hub, sender, env, msg)
+	case env.To != "":
+		local := hub.SendToUser(env.To, msg)
+		delivered = r.relayRemote(hub, sender, env, msg) || local
+	default:
+		hub.Broadcast(sender.SessionID, msg)
+		r.relayRemote(hub, sender, env, msg)
+	}
+
+	if !delivered {
+		sender.Send(messaging.ErrorFrame(messaging.ErrRecipientNotFound, env.Type))
+	}
+}
+
+// AddClient attaches client to the room hub. Non-empty sessionID resumes previous session of the client within the grace period
+func (r *Connections) AddClient(c *messaging.Client, capacity int, sessionID string) (*RoomHub, error) {
+	// storage is not queried under r.mu
+	isHost := r.IsHost(c.RoomID, c.UserID)
+
 	r.mu.Lock()
-	r.WsClients[c.UserID] = c
-	r.mu.Unlock()
-	log.Printf("👋 %s established connection with room %s", c.Username, roomID)
+	defer r.mu.Unlock()
+
+	hub, ok := r.rooms[c.RoomID]
+	if !ok {
+		hub = newRoomHub(c.RoomID, capacity)
+		r.rooms[c.RoomID] = hub
+	} else {
+		hub.setCapacity(capacity)
+	}
+
+	backlog, resumed, err := hub.attach(c, sessionID, len(r.remote[c.RoomID]))
+	if err != nil {
+		if !ok {
+			delete(r.rooms, c.RoomID)
+		}
+		return nil, err
+	}
+
+	if resumed {
+		log.Printf("🔄 %s resumed session %s in room %s (%d buffered)", c.Username, c.SessionID, c.RoomID, len(backlog))
+	} else {
+		log.Printf("👋 %s established connection with room %s", c.Username, c.RoomID)
+	}
+
+	c.Send(messaging.Frame(messaging.TypeSession, messaging.SessionPayload{SessionID: c.SessionID, Resumed: resumed}))
+	roster := hub.Roster(c)
+	roster.Peers = append(roster.Peers, r.remoteRoster(c.RoomID)...)
+	roster.Hands = r.roomHands(c.RoomID)
+	c.Send(messaging.Frame(messaging.TypeRoster, roster))
+	for _, msg := range backlog {
+		c.Send(msg)
+	}
+
+	if !resumed && isHost {
+		for _, req := range r.pendingKnocks(c.RoomID) {
+			c.Send(messaging.Frame(messaging.TypeKnock, knockPayload(req)))
+		}
+	}
+
+	if !resumed {
+		peer, _ := hub.Peer(c.SessionID)
+		hub.Broadcast(c.SessionID, messaging.Frame(messaging.TypePeerJoined, peer))
+		r.publish(clusterEvent{Kind: clusterJoin, RoomID: c.RoomID, Peer: &peer})
+		r.recordJoin(c.RoomID, peer)
+	}
+
+	return hub, nil
 }
 
-func (r *Connections) RemoveClient(c *messaging.Client, roomID string) {
+// RemoveClient detaches client from its session. Peers are notified only when session is not resumed within the grace period
+func (r *Connections) RemoveClient(c *messaging.Client) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if _, ok := r.WsClients[c.UserID]; ok {
-		delete(r.WsClients, c.UserID)
-		log.Printf("👤 %s disconnected from room %s", c.Username, roomID)
+	defer c.Close()
+
+	hub, ok := r.rooms[c.RoomID]
+	if !ok {
+		return
+	}
+
+	sess, ok := hub.detach(c, r.cfg.SessionGrace, func(sess *session) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		r.dropSession(hub, sess)
+	})
+	if !ok {
+		return
+	}
+
+	if r.cfg.SessionGrace > 0 {
+		log.Printf("⏸️ %s detached from room %s, session %s kept for %s", c.Username, c.RoomID, sess.ID, r.cfg.SessionGrace)
+		return
+	}
+
+	r.dropSession(hub, sess)
+}
+
+// dropSession must be called with r.mu held
+func (r *Connections) dropSession(hub *RoomHub, sess *session) {
+	removed, empty := hub.drop(sess)
+	if removed {
+		log.Printf("👤 %s disconnected from room %s", sess.Username, hub.RoomID)
+		peer := sess.peer()
+		hub.Broadcast(sess.ID, messaging.Frame(messaging.TypePeerLeft, peer))
+		r.publish(clusterEvent{Kind: clusterLeave, RoomID: hub.RoomID, Peer: &peer})
+		r.recordLeave(hub.RoomID, sess.ID)
+	}
+
+	if empty && r.rooms[hub.RoomID] == hub {
+		delete(r.rooms, hub.RoomID)
+		log.Printf("room %s hub closed, no clients left", hub.RoomID)
+	}
+
+	if removed {
+		r.clearHand(hub.RoomID, sess.UserID)
+	}
+}
+
+func (r *Connections) Hub(roomID string) (*RoomHub, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	hub, ok := r.rooms[roomID]
+
+	return hub, ok
+}
+
+// OwnsSession reports whether the user has the session in the room on this instance, so that connecting with its ID resumes it
+func (r *Connections) OwnsSession(roomID, sessionID, userID string) bool {
+	if sessionID == "" {
+		return false
+	}
+
+	hub, ok := r.Hub(roomID)
+	if !ok {
+		return false
+	}
+
+	peer, ok := hub.Peer(sessionID)
+
+	return ok && peer.UserID == userID
+}
+
+// RoomUserIDs returns IDs of users currently connected to the room on any instance
+func (r *Connections) RoomUserIDs(roomID string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var ids []string
+	if hub, ok := r.rooms[roomID]; ok {
+		ids = hub.UserIDs()
+	}
+
+	for _, p := range r.remote[roomID] {
+		if !slices.Contains(ids, p.UserID) {
+			ids = append(ids, p.UserID)
+		}
+	}
+
+	return ids
+}
+
+// RoomSize returns number of devices currently connected to the room on any instance
+func (r *Connections) RoomSize(roomID string) int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	size := len(r.remote[roomID])
+	if hub, ok := r.rooms[roomID]; ok {
+		size += hub.Len()
 	}
 
-	c.Close()
+	return size
 }
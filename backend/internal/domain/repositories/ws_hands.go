@@ -0,0 +1,91 @@
+package repositories
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+	"videocall/internal/infrastructure/messaging"
+)
+
+// hand raises or lowers hand of the sender. Queue is replicated to every instance and sent to the room after each change
+func (r *Connections) hand(sender *messaging.Client, hub *RoomHub, env *messaging.Envelope) {
+	h := messaging.Hand{
+		UserID:   sender.UserID,
+		Username: sender.Username,
+		RaisedAt: time.Now().UnixMilli(),
+	}
+	raised := env.Type == messaging.TypeRaiseHand
+
+	r.mu.Lock()
+	changed := r.setHand(hub.RoomID, h, raised)
+	r.mu.Unlock()
+
+	if changed {
+		r.publish(clusterEvent{Kind: clusterHand, RoomID: hub.RoomID, Hand: &h, Raised: raised})
+	}
+}
+
+// react forwards a reaction from the allowed set to the room
+func (r *Connections) react(sender *messaging.Client, hub *RoomHub, env *messaging.Envelope) {
+	var payload messaging.ReactionPayload
+	if err := json.Unmarshal(env.Payload, &payload); err != nil || !messaging.Reactions[payload.Emoji] {
+		sender.Send(messaging.ErrorFrame(messaging.ErrInvalidReaction, env.Type))
+		return
+	}
+
+	env.To = ""
+	env.ToSession = ""
+	r.forward(sender, hub, env)
+}
+
+// setHand updates the queue and tells local participants. Must be called with r.mu held
+func (r *Connections) setHand(roomID string, h messaging.Hand, raised bool) bool {
+	hands := r.hands[roomID]
+	i := slices.IndexFunc(hands, func(cur messaging.Hand) bool {
+		return cur.UserID == h.UserID
+	})
+
+	switch {
+	case raised && i < 0:
+		hands = append(hands, h)
+		slices.SortStableFunc(hands, func(a, b messaging.Hand) int {
+			return int(a.RaisedAt - b.RaisedAt)
+		})
+	case !raised && i >= 0:
+		hands = slices.Delete(hands, i, i+1)
+	default:
+		return false
+	}
+
+	if len(hands) == 0 {
+		delete(r.hands, roomID)
+	} else {
+		r.hands[roomID] = hands
+	}
+
+	if hub, ok := r.rooms[roomID]; ok {
+		hub.Broadcast("", messaging.Frame(messaging.TypeHands, messaging.HandsPayload{Hands: r.roomHands(roomID)}))
+	}
+
+	return true
+}
+
+// clearHand lowers hand of the user who has no device left in the room. Must be called with r.mu held
+func (r *Connections) clearHand(roomID, userID string) {
+	if hub, ok := r.rooms[roomID]; ok && slices.Contains(hub.UserIDs(), userID) {
+		return
+	}
+
+	for _, p := range r.remote[roomID] {
+		if p.UserID == userID {
+			return
+		}
+	}
+
+	r.setHand(roomID, messaging.Hand{UserID: userID}, false)
+}
+
+// roomHands returns copy of the queue. Must be called with r.mu held
+func (r *Connections) roomHands(roomID string) []messaging.Hand {
+	return append(make([]messaging.Hand, 0, len(r.hands[roomID])), r.hands[roomID]...)
+}
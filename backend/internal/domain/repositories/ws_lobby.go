@@ -0,0 +1,220 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+	"videocall/internal/infrastructure/messaging"
+
+	"github.com/google/uuid"
+)
+
+// Lobby request statuses
+const (
+	LobbyPending  = "pending"
+	LobbyAdmitted = "admitted"
+	LobbyDenied   = "denied"
+	LobbyTimedOut = "timed_out"
+)
+
+var (
+	ErrLobbyRequestNotFound = errors.New("lobby request not found")
+	ErrLobbyRequestResolved = errors.New("lobby request already resolved")
+)
+
+// LobbyRequest is a join request waiting for the host. Requests are replicated to every instance over the bus,
+// so requester may poll and host may decide on any of them
+type LobbyRequest struct {
+	ID        string    `json:"id"`
+	RoomID    string    `json:"room_id"`
+	UserID    string    `json:"user_id"`
+	Username  string    `json:"username"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	// HostID is told when the request is resolved
+	HostID string `json:"host_id"`
+}
+
+type lobbyEntry struct {
+	LobbyRequest
+	origin   string
+	resolved chan struct{}
+}
+
+// Knock parks join request of the user and notifies the host. Repeated knock returns the pending request.
+// Reports whether the host got the knock live
+func (r *Connections) Knock(roomID, hostID, userID, username string) (LobbyRequest, bool) {
+	r.mu.Lock()
+	for _, e := range r.lobby {
+		if e.RoomID == roomID && e.UserID == userID && e.Status == LobbyPending {
+			r.mu.Unlock()
+			return e.LobbyRequest, true
+		}
+	}
+
+	req := LobbyRequest{
+		ID:        uuid.NewString(),
+		RoomID:    roomID,
+		UserID:    userID,
+		Username:  username,
+		HostID:    hostID,
+		Status:    LobbyPending,
+		CreatedAt: time.Now(),
+	}
+	r.addLobbyEntry(req, r.nodeID)
+	r.mu.Unlock()
+
+	r.publish(clusterEvent{Kind: clusterKnock, RoomID: roomID, Lobby: &req})
+
+	log.Printf("🚪 %s knocks at room %s", username, roomID)
+
+	delivered := r.Deliver(roomID, hostID, messaging.NewEnvelope(messaging.TypeKnock, knockPayload(req)))
+
+	return req, delivered
+}
+
+// WaitLobby returns the request once it is resolved or wait passes
+func (r *Connections) WaitLobby(ctx context.Context, requestID string, wait time.Duration) (LobbyRequest, error) {
+	r.mu.RLock()
+	e, ok := r.lobby[requestID]
+	r.mu.RUnlock()
+	if !ok {
+		return LobbyRequest{}, ErrLobbyRequestNotFound
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-e.resolved:
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return e.LobbyRequest, nil
+}
+
+// ResolveLobby admits or denies pending request of the room
+func (r *Connections) ResolveLobby(roomID, requestID string, admit bool) (LobbyRequest, error) {
+	status := LobbyDenied
+	if admit {
+		status = LobbyAdmitted
+	}
+
+	r.mu.Lock()
+	e, ok := r.lobby[requestID]
+	if !ok || e.RoomID != roomID {
+		r.mu.Unlock()
+		return LobbyRequest{}, ErrLobbyRequestNotFound
+	}
+	if e.Status != LobbyPending {
+		r.mu.Unlock()
+		return e.LobbyRequest, ErrLobbyRequestResolved
+	}
+	r.resolveLobbyEntry(e, status)
+	req := e.LobbyRequest
+	r.mu.Unlock()
+
+	r.publish(clusterEvent{Kind: clusterLobby, RoomID: roomID, Lobby: &req})
+
+	log.Printf("🚪 %s %s in room %s", req.Username, status, roomID)
+
+	return req, nil
+}
+
+// PendingKnocks returns requests of the room waiting for the host
+func (r *Connections) PendingKnocks(roomID string) []LobbyRequest {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.pendingKnocks(roomID)
+}
+
+// pendingKnocks must be called with r.mu held
+func (r *Connections) pendingKnocks(roomID string) []LobbyRequest {
+	reqs := make([]LobbyRequest, 0)
+	for _, e := range r.lobby {
+		if e.RoomID == roomID && e.Status == LobbyPending {
+			reqs = append(reqs, e.LobbyRequest)
+		}
+	}
+
+	return reqs
+}
+
+// addLobbyEntry must be called with r.mu held
+func (r *Connections) addLobbyEntry(req LobbyRequest, origin string) {
+	if _, ok := r.lobby[req.ID]; ok {
+		return
+	}
+
+	r.lobby[req.ID] = &lobbyEntry{
+		LobbyRequest: req,
+		origin:       origin,
+		resolved:     make(chan struct{}),
+	}
+}
+
+// resolveLobbyEntry must be called with r.mu held. Host devices connected here are told the knock is gone
+func (r *Connections) resolveLobbyEntry(e *lobbyEntry, status string) {
+	if e.Status != LobbyPending {
+		return
+	}
+	e.Status = status
+	close(e.resolved)
+
+	if hub, ok := r.rooms[e.RoomID]; ok {
+		hub.SendToUser(e.HostID, messaging.Frame(messaging.TypeKnockResolved, knockPayload(e.LobbyRequest)))
+	}
+}
+
+// onLobbyEvent applies request replicated from another instance. Must be called with r.mu held
+func (r *Connections) onLobbyEvent(node string, req *LobbyRequest) {
+	if req == nil {
+		return
+	}
+
+	r.addLobbyEntry(LobbyRequest{
+		ID:        req.ID,
+		RoomID:    req.RoomID,
+		UserID:    req.UserID,
+		Username:  req.Username,
+		HostID:    req.HostID,
+		Status:    LobbyPending,
+		CreatedAt: req.CreatedAt,
+	}, node)
+
+	if req.Status != LobbyPending {
+		r.resolveLobbyEntry(r.lobby[req.ID], req.Status)
+	}
+}
+
+// expireLobby times out requests the host did not answer and forgets resolved ones.
+// Every instance expires its copy by the same deadline. Must be called with r.mu held
+func (r *Connections) expireLobby() {
+	for id, e := range r.lobby {
+		age := time.Since(e.CreatedAt)
+
+		if e.Status == LobbyPending && age > r.lobbyTimeout {
+			r.resolveLobbyEntry(e, LobbyTimedOut)
+		}
+
+		// resolved requests are kept for a while so that requester still learns the outcome
+		if e.Status != LobbyPending && age > 2*r.lobbyTimeout {
+			delete(r.lobby, id)
+		}
+	}
+}
+
+func knockPayload(req LobbyRequest) messaging.KnockPayload {
+	return messaging.KnockPayload{
+		RequestID: req.ID,
+		UserID:    req.UserID,
+		Username:  req.Username,
+		Status:    req.Status,
+	}
+}
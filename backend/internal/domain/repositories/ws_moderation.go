@@ -0,0 +1,194 @@
+package repositories
+
+import (
+	"encoding/json"
+	"log"
+	"videocall/internal/infrastructure/messaging"
+)
+
+// IsHost reports whether the user created the room and so may moderate it
+func (r *Connections) IsHost(roomID, userID string) bool {
+	room, ok := r.roomRepository.GetRoom(roomID)
+
+	return ok && room.CreatorUserID == userID
+}
+
+// Banned reports whether the user was kicked from the room and may not rejoin
+func (r *Connections) Banned(roomID, userID string) bool {
+	return r.roomRepository.IsBanned(roomID, userID)
+}
+
+// Kick disconnects all devices of the user on every instance and blocks rejoining while the room exists.
+// Ban is kept in room storage, so every instance sees it, also after restart. Reports whether the user was in the room
+func (r *Connections) Kick(roomID, userID, reason string) bool {
+	r.roomRepository.BanUser(roomID, userID)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	present := r.kickLocal(roomID, userID, reason)
+	for _, p := range r.remote[roomID] {
+		if p.UserID == userID {
+			present = true
+			break
+		}
+	}
+
+	r.publish(clusterEvent{Kind: clusterKick, RoomID: roomID, ToUser: userID, Reason: reason})
+
+	return present
+}
+
+// EndRoom disconnects everyone on every instance and deletes chat history of the meeting.
+// Persistent room, a recurring series or a room with slug, is kept for the next meeting, any other room is deleted
+func (r *Connections) EndRoom(roomID, reason string) {
+	room, ok := r.roomRepository.GetRoom(roomID)
+	if ok && room.Persistent() {
+		r.disconnectAll(roomID, reason)
+		r.deleteChat(roomID)
+		return
+	}
+
+	r.DeleteRoom(roomID, reason)
+}
+
+// DeleteRoom disconnects everyone on every instance and deletes the room with its chat history, persistent or not
+func (r *Connections) DeleteRoom(roomID, reason string) {
+	r.disconnectAll(roomID, reason)
+
+	r.roomRepository.DeleteRoom(roomID)
+	r.deleteChat(roomID)
+}
+
+func (r *Connections) disconnectAll(roomID, reason string) {
+	r.mu.Lock()
+	r.endLocal(roomID, reason)
+	r.mu.Unlock()
+
+	r.publish(clusterEvent{Kind: clusterEnd, RoomID: roomID, Reason: reason})
+}
+
+func (r *Connections) deleteChat(roomID string) {
+	if err := r.chats.DeleteRoomMessages(roomID); err != nil {
+		log.Printf("failed to delete chat history of ended room %s: %v", roomID, err)
+	}
+}
+
+// Deliver sends server-built message to all devices of the user on any instance
+func (r *Connections) Deliver(roomID, userID string, env *messaging.Envelope) bool {
+	msg, err := env.Encode()
+	if err != nil {
+		log.Printf("failed to encode %s message: %v", env.Type, err)
+		return false
+	}
+
+	r.mu.RLock()
+	delivered := false
+	if hub, ok := r.rooms[roomID]; ok {
+		delivered = hub.SendToUser(userID, msg)
+	}
+	remote := false
+	for _, p := range r.remote[roomID] {
+		if p.UserID == userID {
+			remote = true
+			break
+		}
+	}
+	r.mu.RUnlock()
+
+	if remote {
+		r.publish(clusterEvent{Kind: clusterRelay, RoomID: roomID, ToUser: userID, Frame: msg})
+	}
+
+	return delivered || remote
+}
+
+// moderate executes host command received over signaling
+func (r *Connections) moderate(sender *messaging.Client, hub *RoomHub, env *messaging.Envelope) {
+	if !r.IsHost(hub.RoomID, sender.UserID) {
+		sender.Send(messaging.ErrorFrame(messaging.ErrForbidden, env.Type))
+		return
+	}
+
+	var payload messaging.ModerationPayload
+	if len(env.Payload) > 0 {
+		if err := json.Unmarshal(env.Payload, &payload); err != nil {
+			sender.Send(messaging.ErrorFrame(messaging.ErrMalformedMessage, env.Type))
+			return
+		}
+	}
+
+	switch env.Type {
+	case messaging.TypeKick:
+		if env.To == "" || env.To == sender.UserID {
+			sender.Send(messaging.ErrorFrame(messaging.ErrTargetRequired, env.Type))
+			return
+		}
+		if !r.Kick(hub.RoomID, env.To, payload.Reason) {
+			sender.Send(messaging.ErrorFrame(messaging.ErrRecipientNotFound, env.Type))
+		}
+	case messaging.TypeMuteRequest:
+		if env.To == "" && env.ToSession == "" {
+			sender.Send(messaging.ErrorFrame(messaging.ErrTargetRequired, env.Type))
+			return
+		}
+		r.forward(sender, hub, env)
+	case messaging.TypeEndMeeting:
+		r.EndRoom(hub.RoomID, payload.Reason)
+	case messaging.TypeAdmit, messaging.TypeDeny:
+		if _, err := r.ResolveLobby(hub.RoomID, payload.RequestID, env.Type == messaging.TypeAdmit); err != nil {
+			sender.Send(messaging.ErrorFrame(err, env.Type))
+		}
+	}
+}
+
+// kickLocal must be called with r.mu held
+func (r *Connections) kickLocal(roomID, userID, reason string) bool {
+	hub, ok := r.rooms[roomID]
+	if !ok {
+		return false
+	}
+
+	evicted := r.evict(hub, userID, messaging.CloseKicked, reason)
+	if len(evicted) > 0 {
+		log.Printf("🚫 %s kicked from room %s", evicted[0].Username, roomID)
+	}
+
+	return len(evicted) > 0
+}
+
+// endLocal must be called with r.mu held
+func (r *Connections) endLocal(roomID, reason string) {
+	delete(r.remote, roomID)
+	delete(r.hands, roomID)
+
+	if hub, ok := r.rooms[roomID]; ok {
+		r.evict(hub, "", messaging.CloseRoomEnded, reason)
+		log.Printf("room %s ended by host", roomID)
+	}
+}
+
+// evict closes sessions of the user (all when userID is empty) skipping grace period. Must be called with r.mu held
+func (r *Connections) evict(hub *RoomHub, userID string, code int, reason string) []*session {
+	evicted, empty := hub.evict(userID)
+	for _, sess := range evicted {
+		if c := sess.evict(); c != nil {
+			go c.CloseWithReason(code, reason)
+		}
+
+		peer := sess.peer()
+		hub.Broadcast("", messaging.Frame(messaging.TypePeerLeft, peer))
+		r.publish(clusterEvent{Kind: clusterLeave, RoomID: hub.RoomID, Peer: &peer})
+		r.recordLeave(hub.RoomID, sess.ID)
+	}
+
+	if empty && r.rooms[hub.RoomID] == hub {
+		delete(r.rooms, hub.RoomID)
+	}
+
+	for _, sess := range evicted {
+		r.clearHand(hub.RoomID, sess.UserID)
+	}
+
+	return evicted
+}
@@ -0,0 +1,51 @@
+package repositories
+
+import (
+	"slices"
+	"time"
+	"videocall/internal/infrastructure/messaging"
+)
+
+// Occupant is a user connected to the room with one or more devices
+type Occupant struct {
+	UserID   string
+	Username string
+	// JoinedAt is when the first of the devices joined
+	JoinedAt time.Time
+	Devices  int
+}
+
+// Occupancy returns users connected to the room on any instance, in order of joining
+func (r *Connections) Occupancy(roomID string) []Occupant {
+	r.mu.RLock()
+	peers := make([]messaging.Peer, 0)
+	if hub, ok := r.rooms[roomID]; ok {
+		peers = append(peers, hub.Peers()...)
+	}
+	for _, p := range r.remote[roomID] {
+		peers = append(peers, p.Peer)
+	}
+	r.mu.RUnlock()
+
+	var occupants []Occupant
+	for _, p := range peers {
+		joinedAt := time.UnixMilli(p.JoinedAt)
+
+		i := slices.IndexFunc(occupants, func(o Occupant) bool { return o.UserID == p.UserID })
+		if i < 0 {
+			occupants = append(occupants, Occupant{UserID: p.UserID, Username: p.Username, JoinedAt: joinedAt, Devices: 1})
+			continue
+		}
+
+		occupants[i].Devices++
+		if joinedAt.Before(occupants[i].JoinedAt) {
+			occupants[i].JoinedAt = joinedAt
+		}
+	}
+
+	slices.SortFunc(occupants, func(a, b Occupant) int {
+		return a.JoinedAt.Compare(b.JoinedAt)
+	})
+
+	return occupants
+}
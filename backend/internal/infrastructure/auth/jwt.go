@@ -32,7 +32,7 @@ func (j *JWT) Issue(userID, username, roomID string) (string, *jwt.Token, error)
 		username,
 		roomID,
 		jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(j.Ttl * time.Second)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(j.Ttl)),
 			Issuer:    "test",
 		},
 	})
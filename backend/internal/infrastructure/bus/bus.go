@@ -0,0 +1,96 @@
+package bus
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"videocall/internal/infrastructure/config"
+)
+
+const (
+	TypeLocal = "local"
+	TypeMesh  = "mesh"
+)
+
+// subscriberQueueSize how many messages may wait for a slow subscriber before they are dropped
+const subscriberQueueSize = 1024
+
+type Handler func(data []byte)
+
+// Bus carries signaling events between backend instances.
+// Published message is delivered to every subscriber of every instance, including the publishing one
+type Bus interface {
+	Publish(data []byte) error
+	Subscribe(h Handler)
+	Close() error
+}
+
+// New creates a bus based on configured type
+func New(ctx context.Context, cfg config.Bus) (Bus, error) {
+	switch cfg.Type {
+	case TypeLocal, "":
+		return NewLocal(), nil
+	case TypeMesh:
+		return NewMesh(ctx, cfg)
+	default:
+		return nil, fmt.Errorf("unknown bus type %q", cfg.Type)
+	}
+}
+
+// subscribers dispatches messages to handlers, each handler has own goroutine so publisher never waits for it
+type subscribers struct {
+	queues []chan []byte
+	closed bool
+	mu     sync.RWMutex
+}
+
+func (s *subscribers) add(h Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+
+	queue := make(chan []byte, subscriberQueueSize)
+	s.queues = append(s.queues, queue)
+
+	go func() {
+		for data := range queue {
+			h(data)
+		}
+	}()
+}
+
+func (s *subscribers) dispatch(data []byte) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// queues of a closed bus are closed too, instances may still publish while shutting down
+	if s.closed {
+		return
+	}
+
+	for _, queue := range s.queues {
+		select {
+		case queue <- data:
+		default:
+			log.Printf("bus: subscriber queue full, dropping message")
+		}
+	}
+}
+
+func (s *subscribers) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+	s.closed = true
+
+	for _, queue := range s.queues {
+		close(queue)
+	}
+}
@@ -0,0 +1,24 @@
+package bus
+
+// Local is an in-process bus. Single instance deployments use it, several Connections sharing it behave like a cluster
+type Local struct {
+	subs subscribers
+}
+
+func NewLocal() *Local {
+	return &Local{}
+}
+
+func (b *Local) Publish(data []byte) error {
+	b.subs.dispatch(data)
+	return nil
+}
+
+func (b *Local) Subscribe(h Handler) {
+	b.subs.add(h)
+}
+
+func (b *Local) Close() error {
+	b.subs.close()
+	return nil
+}
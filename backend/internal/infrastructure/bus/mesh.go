@@ -0,0 +1,237 @@
+package bus
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"time"
+	"videocall/internal/infrastructure/config"
+)
+
+const (
+	meshMaxFrame         = 1 << 20
+	meshRetryInterval    = 2 * time.Second
+	meshHandshakeTimeout = 5 * time.Second
+	meshWriteTimeout     = 5 * time.Second
+)
+
+var ErrFrameTooLarge = errors.New("bus frame too large")
+
+// Mesh is a peer-to-peer TCP bus between backend instances. Every node listens for inbound links
+// and dials all configured peers; messages are sent over outbound links only, so a symmetric peer list
+// delivers each message exactly once. Links are authenticated with a token derived from shared secret,
+// the mesh is meant for a private network
+type Mesh struct {
+	token    []byte
+	listener net.Listener
+	peers    []*meshPeer
+	subs     subscribers
+	cancel   context.CancelFunc
+}
+
+type meshPeer struct {
+	addr string
+	out  chan []byte
+}
+
+func NewMesh(ctx context.Context, cfg config.Bus) (*Mesh, error) {
+	if cfg.Secret == "" {
+		return nil, errors.New("BUS_SECRET is required for mesh bus")
+	}
+
+	listener, err := net.Listen("tcp", cfg.ListenAddr)
+	if err != nil {
+		return nil, fmt.Errorf("bus listen: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	m := &Mesh{
+		token:    meshToken(cfg.Secret),
+		listener: listener,
+		cancel:   cancel,
+	}
+
+	for _, addr := range cfg.Peers {
+		addr = strings.TrimSpace(addr)
+		if addr == "" {
+			continue
+		}
+
+		p := &meshPeer{
+			addr: addr,
+			out:  make(chan []byte, subscriberQueueSize),
+		}
+		m.peers = append(m.peers, p)
+		go m.dialLoop(ctx, p)
+	}
+
+	go m.acceptLoop(ctx)
+	context.AfterFunc(ctx, func() {
+		_ = listener.Close()
+	})
+
+	log.Printf("✅ Mesh bus listening on %s, peers: %v", listener.Addr(), cfg.Peers)
+
+	return m, nil
+}
+
+func (m *Mesh) Publish(data []byte) error {
+	if len(data) > meshMaxFrame {
+		return ErrFrameTooLarge
+	}
+
+	m.subs.dispatch(data)
+
+	for _, p := range m.peers {
+		select {
+		case p.out <- data:
+		default:
+			log.Printf("bus: queue to %s full, dropping message", p.addr)
+		}
+	}
+
+	return nil
+}
+
+func (m *Mesh) Subscribe(h Handler) {
+	m.subs.add(h)
+}
+
+func (m *Mesh) Close() error {
+	m.cancel()
+	m.subs.close()
+	return nil
+}
+
+func (m *Mesh) acceptLoop(ctx context.Context) {
+	for {
+		conn, err := m.listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("bus: accept error: %v", err)
+			time.Sleep(meshRetryInterval)
+			continue
+		}
+
+		go m.serve(ctx, conn)
+	}
+}
+
+// serve reads messages from inbound link
+func (m *Mesh) serve(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.Close()
+	})
+	defer stop()
+
+	r := bufio.NewReader(conn)
+
+	_ = conn.SetReadDeadline(time.Now().Add(meshHandshakeTimeout))
+	hello, err := readFrame(r)
+	if err != nil || !hmac.Equal(hello, m.token) {
+		log.Printf("bus: rejected link from %s", conn.RemoteAddr())
+		return
+	}
+	_ = conn.SetReadDeadline(time.Time{})
+
+	log.Printf("bus: inbound link from %s established", conn.RemoteAddr())
+
+	for {
+		data, err := readFrame(r)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("bus: inbound link from %s lost: %v", conn.RemoteAddr(), err)
+			}
+			return
+		}
+
+		m.subs.dispatch(data)
+	}
+}
+
+// dialLoop keeps outbound link to the peer, reconnecting on failures
+func (m *Mesh) dialLoop(ctx context.Context, p *meshPeer) {
+	dialer := &net.Dialer{Timeout: meshHandshakeTimeout}
+
+	for {
+		conn, err := dialer.DialContext(ctx, "tcp", p.addr)
+		if err == nil {
+			log.Printf("bus: outbound link to %s established", p.addr)
+			m.writeLoop(ctx, conn, p)
+			_ = conn.Close()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(meshRetryInterval):
+		}
+	}
+}
+
+func (m *Mesh) writeLoop(ctx context.Context, conn net.Conn, p *meshPeer) {
+	_ = conn.SetWriteDeadline(time.Now().Add(meshWriteTimeout))
+	if err := writeFrame(conn, m.token); err != nil {
+		log.Printf("bus: handshake with %s failed: %v", p.addr, err)
+		return
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case data := <-p.out:
+			_ = conn.SetWriteDeadline(time.Now().Add(meshWriteTimeout))
+			if err := writeFrame(conn, data); err != nil {
+				log.Printf("bus: outbound link to %s lost: %v", p.addr, err)
+				return
+			}
+		}
+	}
+}
+
+func meshToken(secret string) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte("videocall-bus-v1"))
+	return h.Sum(nil)
+}
+
+// writeFrame writes length-prefixed frame
+func writeFrame(w io.Writer, data []byte) error {
+	buf := make([]byte, 4+len(data))
+	binary.BigEndian.PutUint32(buf, uint32(len(data)))
+	copy(buf[4:], data)
+
+	_, err := w.Write(buf)
+	return err
+}
+
+func readFrame(r io.Reader) ([]byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+
+	size := binary.BigEndian.Uint32(header[:])
+	if size > meshMaxFrame {
+		return nil, ErrFrameTooLarge
+	}
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
@@ -0,0 +1,207 @@
+package bus
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"testing"
+	"time"
+	"videocall/internal/infrastructure/config"
+)
+
+func TestFrameRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	for _, msg := range [][]byte{[]byte("hello"), {}, bytes.Repeat([]byte("x"), 70000)} {
+		if err := writeFrame(&buf, msg); err != nil {
+			t.Fatalf("writeFrame: %v", err)
+		}
+	}
+
+	for _, want := range []int{5, 0, 70000} {
+		data, err := readFrame(&buf)
+		if err != nil {
+			t.Fatalf("readFrame: %v", err)
+		}
+		if len(data) != want {
+			t.Fatalf("frame length %d, want %d", len(data), want)
+		}
+	}
+
+	if _, err := readFrame(&buf); err == nil {
+		t.Fatal("readFrame on empty stream succeeded")
+	}
+}
+
+func TestReadFrameTooLarge(t *testing.T) {
+	var header [4]byte
+	binary.BigEndian.PutUint32(header[:], meshMaxFrame+1)
+
+	if _, err := readFrame(bytes.NewReader(header[:])); !errors.Is(err, ErrFrameTooLarge) {
+		t.Fatalf("readFrame error %v, want %v", err, ErrFrameTooLarge)
+	}
+}
+
+func TestReadFrameTruncated(t *testing.T) {
+	var buf bytes.Buffer
+	_ = writeFrame(&buf, []byte("hello"))
+
+	if _, err := readFrame(bytes.NewReader(buf.Bytes()[:6])); err == nil {
+		t.Fatal("readFrame of truncated frame succeeded")
+	}
+}
+
+func TestMeshDelivers(t *testing.T) {
+	addrA, addrB := freeAddr(t), freeAddr(t)
+	a := newTestMesh(t, addrA, "secret", addrB)
+	b := newTestMesh(t, addrB, "secret", addrA)
+
+	gotA, gotB := subscribe(a), subscribe(b)
+
+	if err := a.Publish([]byte("from a")); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if err := b.Publish([]byte("from b")); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	// every message reaches every subscriber of every instance exactly once
+	for name, got := range map[string]chan []byte{"a": gotA, "b": gotB} {
+		received := map[string]int{}
+		for range 2 {
+			received[string(receive(t, got))]++
+		}
+		if received["from a"] != 1 || received["from b"] != 1 {
+			t.Fatalf("node %s received %v", name, received)
+		}
+		expectNothing(t, got)
+	}
+}
+
+func TestMeshRejectsLargeMessage(t *testing.T) {
+	m := newTestMesh(t, freeAddr(t), "secret")
+
+	if err := m.Publish(make([]byte, meshMaxFrame+1)); !errors.Is(err, ErrFrameTooLarge) {
+		t.Fatalf("Publish error %v, want %v", err, ErrFrameTooLarge)
+	}
+}
+
+func TestMeshHandshake(t *testing.T) {
+	addr := freeAddr(t)
+	m := newTestMesh(t, addr, "secret")
+	got := subscribe(m)
+
+	// link with wrong secret is dropped before any message is accepted
+	conn := dial(t, addr)
+	_ = writeFrame(conn, meshToken("wrong"))
+	_ = writeFrame(conn, []byte("forged"))
+
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err == nil {
+		t.Fatal("link with wrong secret was not closed")
+	}
+	expectNothing(t, got)
+
+	conn = dial(t, addr)
+	_ = writeFrame(conn, meshToken("secret"))
+	_ = writeFrame(conn, []byte("genuine"))
+
+	if data := receive(t, got); string(data) != "genuine" {
+		t.Fatalf("received %q, want %q", data, "genuine")
+	}
+}
+
+func TestMeshRequiresSecret(t *testing.T) {
+	if _, err := NewMesh(context.Background(), config.Bus{ListenAddr: freeAddr(t)}); err == nil {
+		t.Fatal("mesh without secret was created")
+	}
+}
+
+func TestPublishAfterClose(t *testing.T) {
+	m := newTestMesh(t, freeAddr(t), "secret")
+	subscribe(m)
+
+	_ = m.Close()
+
+	if err := m.Publish([]byte("late")); err != nil {
+		t.Fatalf("Publish after Close: %v", err)
+	}
+}
+
+func newTestMesh(t *testing.T, addr, secret string, peers ...string) *Mesh {
+	t.Helper()
+
+	m, err := NewMesh(context.Background(), config.Bus{
+		ListenAddr: addr,
+		Secret:     secret,
+		Peers:      peers,
+	})
+	if err != nil {
+		t.Fatalf("NewMesh: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = m.Close()
+	})
+
+	return m
+}
+
+// freeAddr returns loopback address with a port nobody listens on
+func freeAddr(t *testing.T) string {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer l.Close()
+
+	return l.Addr().String()
+}
+
+func dial(t *testing.T, addr string) net.Conn {
+	t.Helper()
+
+	conn, err := net.DialTimeout("tcp", addr, time.Second)
+	if err != nil {
+		t.Fatalf("dial %s: %v", addr, err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	return conn
+}
+
+func subscribe(b Bus) chan []byte {
+	got := make(chan []byte, 16)
+	b.Subscribe(func(data []byte) {
+		got <- data
+	})
+
+	return got
+}
+
+// receive waits longer than mesh retry interval, peer dialed before the other one listened reconnects by then
+func receive(t *testing.T, got chan []byte) []byte {
+	t.Helper()
+
+	select {
+	case data := <-got:
+		return data
+	case <-time.After(2*meshRetryInterval + time.Second):
+		t.Fatal("message not delivered")
+		return nil
+	}
+}
+
+func expectNothing(t *testing.T, got chan []byte) {
+	t.Helper()
+
+	select {
+	case data := <-got:
+		t.Fatalf("unexpected message %q", data)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
@@ -0,0 +1,146 @@
+package calendar
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ProductID identifies the producer of exported calendars
+const ProductID = "-//videocall//meeting//EN"
+
+const (
+	utcFormat   = "20060102T150405Z"
+	localFormat = "20060102T150405"
+	lineLength  = 75
+)
+
+// Event is a meeting exported as VEVENT
+type Event struct {
+	UID   string
+	Start time.Time
+	End   time.Time
+	// Location of a repeated event, its occurrences follow local time of the zone. Times are written in UTC when nil
+	Location *time.Location
+	Rule     *Rule
+	// ExDates are starts of cancelled occurrences
+	ExDates     []time.Time
+	Summary     string
+	Description string
+	URL         string
+	Created     time.Time
+	Modified    time.Time
+}
+
+// Write builds iCalendar (RFC 5545) object with the events
+func Write(events ...Event) []byte {
+	var b builder
+	b.line("BEGIN:VCALENDAR")
+	b.line("VERSION:2.0")
+	b.line("PRODID:" + ProductID)
+	b.line("CALSCALE:GREGORIAN")
+	b.line("METHOD:PUBLISH")
+
+	var zones []*time.Location
+	for _, e := range events {
+		if e.Location != nil && !slices.ContainsFunc(zones, func(loc *time.Location) bool { return loc.String() == e.Location.String() }) {
+			zones = append(zones, e.Location)
+			b.timezone(e.Location, e.Start, e.zoneUntil())
+		}
+	}
+
+	stamp := time.Now()
+	for _, e := range events {
+		b.line("BEGIN:VEVENT")
+		b.line("UID:" + e.UID)
+		b.line("DTSTAMP:" + utc(stamp))
+		b.line("DTSTART" + e.dateTime(e.Start))
+		b.line("DTEND" + e.dateTime(e.End))
+		if e.Rule != nil {
+			b.line("RRULE:" + e.Rule.String())
+		}
+		for _, ex := range e.ExDates {
+			b.line("EXDATE" + e.dateTime(ex))
+		}
+		b.line("SUMMARY:" + Escape(e.Summary))
+		if e.Description != "" {
+			b.line("DESCRIPTION:" + Escape(e.Description))
+		}
+		if e.URL != "" {
+			b.line("URL;VALUE=URI:" + e.URL)
+			b.line("LOCATION:" + Escape(e.URL))
+		}
+		if !e.Created.IsZero() {
+			b.line("CREATED:" + utc(e.Created))
+		}
+		if !e.Modified.IsZero() {
+			b.line("LAST-MODIFIED:" + utc(e.Modified))
+		}
+		b.line("END:VEVENT")
+	}
+
+	b.line("END:VCALENDAR")
+
+	return []byte(b.String())
+}
+
+func utc(t time.Time) string {
+	return t.UTC().Format(utcFormat)
+}
+
+// dateTime formats date-time property value with its parameters, starting from separator after property name
+func (e *Event) dateTime(t time.Time) string {
+	if e.Location == nil {
+		return ":" + utc(t)
+	}
+
+	return ";TZID=" + e.Location.String() + ":" + t.In(e.Location).Format(localFormat)
+}
+
+// zoneUntil returns till when time zone definition is needed for the event
+func (e *Event) zoneUntil() time.Time {
+	until := e.End
+	if e.Rule != nil {
+		if e.Rule.Until.IsZero() || e.Rule.Count > 0 {
+			until = e.Start.AddDate(zoneYears, 0, 0)
+		} else {
+			until = e.Rule.Until
+		}
+	}
+
+	return until
+}
+
+// Escape quotes TEXT property value, every kind of line break becomes \n
+func Escape(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", `\n`,
+	).Replace(s)
+}
+
+type builder struct {
+	strings.Builder
+}
+
+// line writes content line folded to 75 octets without splitting UTF-8 characters
+func (b *builder) line(s string) {
+	limit := lineLength
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		fmt.Fprintf(b, "%s\r\n ", s[:cut])
+		s = s[cut:]
+		// continuation lines start with a space that counts towards the limit
+		limit = lineLength - 1
+	}
+	b.WriteString(s + "\r\n")
+}
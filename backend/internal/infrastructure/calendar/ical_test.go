@@ -0,0 +1,133 @@
+package calendar
+
+import (
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata"
+	"unicode/utf8"
+)
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"plain", "plain"},
+		{`back\slash`, `back\\slash`},
+		{"a;b,c", `a\;b\,c`},
+		{"crlf\r\nline", `crlf\nline`},
+		{"lf\nline", `lf\nline`},
+		{"cr\rline", `cr\nline`},
+		{"mixed\r\n\n\r", `mixed\n\n\n`},
+	}
+
+	for _, tt := range tests {
+		if got := Escape(tt.in); got != tt.want {
+			t.Errorf("Escape(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestLineFolding(t *testing.T) {
+	summary := strings.Repeat("Планёрка команды ", 20)
+
+	var b builder
+	b.line("SUMMARY:" + summary)
+
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\r\n"), "\r\n")
+	if len(lines) < 2 {
+		t.Fatal("long line was not folded")
+	}
+
+	var unfolded strings.Builder
+	for i, line := range lines {
+		if len(line) > lineLength {
+			t.Errorf("line %d is %d octets long", i, len(line))
+		}
+		if !utf8.ValidString(line) {
+			t.Errorf("line %d splits a character: %q", i, line)
+		}
+		if i > 0 {
+			if !strings.HasPrefix(line, " ") {
+				t.Fatalf("continuation line %d does not start with space", i)
+			}
+			line = line[1:]
+		}
+		unfolded.WriteString(line)
+	}
+
+	if unfolded.String() != "SUMMARY:"+summary {
+		t.Fatal("unfolded line differs from the original")
+	}
+}
+
+func TestRuleString(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+		want string
+	}{
+		{"daily", Rule{Freq: "DAILY", Interval: 1}, "FREQ=DAILY"},
+		{"interval and count", Rule{Freq: "DAILY", Interval: 2, Count: 5}, "FREQ=DAILY;INTERVAL=2;COUNT=5"},
+		{"weekly on days", Rule{Freq: "WEEKLY", ByDay: []time.Weekday{time.Monday, time.Friday}}, "FREQ=WEEKLY;BYDAY=MO,FR"},
+		{"until in utc", Rule{Freq: "MONTHLY", Until: time.Date(2026, 6, 30, 23, 59, 59, 0, time.FixedZone("", 3*3600))}, "FREQ=MONTHLY;UNTIL=20260630T205959Z"},
+		{"count wins over until", Rule{Freq: "DAILY", Count: 3, Until: time.Date(2026, 6, 30, 0, 0, 0, 0, time.UTC)}, "FREQ=DAILY;COUNT=3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rule.String(); got != tt.want {
+				t.Fatalf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteRecurringEvent(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("load zone: %v", err)
+	}
+
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, berlin)
+	ics := string(Write(Event{
+		UID:      "room@videocall",
+		Start:    start,
+		End:      start.Add(30 * time.Minute),
+		Location: berlin,
+		Rule:     &Rule{Freq: "WEEKLY", ByDay: []time.Weekday{time.Monday}, Count: 10},
+		ExDates:  []time.Time{start.AddDate(0, 0, 7)},
+		Summary:  "Standup, daily",
+	}))
+
+	for _, want := range []string{
+		"BEGIN:VTIMEZONE\r\nTZID:Europe/Berlin\r\n",
+		// switch to summer time at 02:00 local on the last Sunday of March
+		"BEGIN:DAYLIGHT\r\nDTSTART:20260329T020000\r\nTZOFFSETFROM:+0100\r\nTZOFFSETTO:+0200\r\n",
+		"BEGIN:STANDARD\r\nDTSTART:20261025T030000\r\nTZOFFSETFROM:+0200\r\nTZOFFSETTO:+0100\r\n",
+		"DTSTART;TZID=Europe/Berlin:20260302T090000\r\n",
+		"DTEND;TZID=Europe/Berlin:20260302T093000\r\n",
+		"RRULE:FREQ=WEEKLY;BYDAY=MO;COUNT=10\r\n",
+		"EXDATE;TZID=Europe/Berlin:20260309T090000\r\n",
+		`SUMMARY:Standup\, daily` + "\r\n",
+	} {
+		if !strings.Contains(ics, want) {
+			t.Errorf("calendar lacks %q", want)
+		}
+	}
+}
+
+func TestWriteUTCEvent(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.FixedZone("", 3*3600))
+	ics := string(Write(Event{UID: "room@videocall", Start: start, End: start.Add(time.Hour), Summary: "Call"}))
+
+	if strings.Contains(ics, "VTIMEZONE") {
+		t.Error("event without location defines a time zone")
+	}
+	for _, want := range []string{"DTSTART:20260302T060000Z\r\n", "DTEND:20260302T070000Z\r\n"} {
+		if !strings.Contains(ics, want) {
+			t.Errorf("calendar lacks %q", want)
+		}
+	}
+}
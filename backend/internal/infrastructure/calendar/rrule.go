@@ -0,0 +1,41 @@
+package calendar
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rule is RRULE of a repeated event
+type Rule struct {
+	// Freq is DAILY, WEEKLY or MONTHLY
+	Freq     string
+	Interval int
+	ByDay    []time.Weekday
+	Count    int
+	Until    time.Time
+}
+
+var weekdays = [...]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, 0, len(r.ByDay))
+		for _, day := range r.ByDay {
+			days = append(days, weekdays[day])
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	} else if !r.Until.IsZero() {
+		// UNTIL must be in UTC when DTSTART has time zone
+		parts = append(parts, "UNTIL="+utc(r.Until))
+	}
+
+	return strings.Join(parts, ";")
+}
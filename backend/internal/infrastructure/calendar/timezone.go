@@ -0,0 +1,64 @@
+package calendar
+
+import (
+	"fmt"
+	"time"
+)
+
+// zoneYears is how far ahead offsets of a time zone are written for an event without known end
+const zoneYears = 10
+
+// timezone writes VTIMEZONE with offsets of the zone in effect from start till until.
+// Go does not expose rules of a zone, so every transition becomes its own observance
+func (b *builder) timezone(loc *time.Location, start, until time.Time) {
+	b.line("BEGIN:VTIMEZONE")
+	b.line("TZID:" + loc.String())
+
+	t := start.In(loc)
+	from, _ := t.ZoneBounds()
+	if from.IsZero() {
+		// zone has no transitions before start
+		from = time.Date(1970, 1, 1, 0, 0, 0, 0, loc)
+	}
+	b.observance(from.Add(-time.Second), from)
+
+	for {
+		_, end := t.ZoneBounds()
+		if end.IsZero() || end.After(until) {
+			break
+		}
+		b.observance(end.Add(-time.Second), end)
+		t = end
+	}
+
+	b.line("END:VTIMEZONE")
+}
+
+// observance writes offset change happening at onset, before is a moment just before it
+func (b *builder) observance(before, onset time.Time) {
+	_, offsetFrom := before.Zone()
+	name, offsetTo := onset.Zone()
+
+	kind := "STANDARD"
+	if onset.IsDST() {
+		kind = "DAYLIGHT"
+	}
+
+	b.line("BEGIN:" + kind)
+	// onset is written in local time of the previous offset
+	b.line("DTSTART:" + onset.UTC().Add(time.Duration(offsetFrom)*time.Second).Format(localFormat))
+	b.line("TZOFFSETFROM:" + offset(offsetFrom))
+	b.line("TZOFFSETTO:" + offset(offsetTo))
+	b.line("TZNAME:" + Escape(name))
+	b.line("END:" + kind)
+}
+
+func offset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign = "-"
+		seconds = -seconds
+	}
+
+	return fmt.Sprintf("%s%02d%02d", sign, seconds/3600, seconds%3600/60)
+}
@@ -2,6 +2,8 @@ package config
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/caarlos0/env/v11"
@@ -9,12 +11,16 @@ import (
 
 type Config struct {
 	Addr string `env:"ADDR" envDefault:":8080"`
+	// PublicURL is where frontend is served, used for links leaving the app. Taken from request when empty
+	PublicURL string `env:"PUBLIC_URL" envDefault:""`
 	JWT
 	RefreshToken
 	Turn
 	VAPID
 	Storage
 	RoomConfig
+	Signaling
+	Bus
 }
 
 type Storage struct {
@@ -50,8 +56,77 @@ type VAPID struct {
 }
 
 type RoomConfig struct {
-	TTL           time.Duration `env:"ROOM_TTL" envDefault:"4h"`
-	CleanInterval time.Duration `env:"ROOM_CLEAN_INTERVAL" envDefault:"60s"`
+	TTL             time.Duration `env:"ROOM_TTL" envDefault:"4h"`
+	CleanInterval   time.Duration `env:"ROOM_CLEAN_INTERVAL" envDefault:"60s"`
+	DefaultCapacity int           `env:"ROOM_DEFAULT_CAPACITY" envDefault:"4"`
+	LobbyTimeout    time.Duration `env:"ROOM_LOBBY_TIMEOUT" envDefault:"2m"`
+	// LobbyWait how long join request long-polls for host decision before answering pending
+	LobbyWait time.Duration `env:"ROOM_LOBBY_WAIT" envDefault:"25s"`
+	// After PasscodeMaxAttempts wrong passcodes within PasscodeWindow the room refuses passcodes until the window passes
+	PasscodeMaxAttempts int           `env:"ROOM_PASSCODE_MAX_ATTEMPTS" envDefault:"5"`
+	PasscodeWindow      time.Duration `env:"ROOM_PASSCODE_WINDOW" envDefault:"5m"`
+	// MaxMeetingDuration limits duration of a scheduled meeting
+	MaxMeetingDuration time.Duration `env:"ROOM_MAX_MEETING_DURATION" envDefault:"24h"`
+}
+
+type Signaling struct {
+	PingInterval time.Duration `env:"SIGNAL_PING_INTERVAL" envDefault:"20s"`
+	PongTimeout  time.Duration `env:"SIGNAL_PONG_TIMEOUT" envDefault:"45s"`
+	WriteTimeout time.Duration `env:"SIGNAL_WRITE_TIMEOUT" envDefault:"10s"`
+	SessionGrace time.Duration `env:"SIGNAL_SESSION_GRACE" envDefault:"15s"`
+
+	// AllowedOrigins empty means same origin only
+	AllowedOrigins []string `env:"SIGNAL_ALLOWED_ORIGINS" envSeparator:","`
+
+	AuthTimeout time.Duration `env:"SIGNAL_AUTH_TIMEOUT" envDefault:"5s"`
+	TicketTTL   time.Duration `env:"SIGNAL_TICKET_TTL" envDefault:"30s"`
+	// ReauthLead how long before jwt expiry client is asked for a fresh token
+	ReauthLead time.Duration `env:"SIGNAL_REAUTH_LEAD" envDefault:"60s"`
+	// AllowQueryToken accepts ?jwt= for old clients, token ends up in access logs
+	AllowQueryToken bool `env:"SIGNAL_ALLOW_QUERY_TOKEN" envDefault:"false"`
+
+	SendBuffer          int           `env:"SIGNAL_SEND_BUFFER" envDefault:"256"`
+	SlowConsumerTimeout time.Duration `env:"SIGNAL_SLOW_CONSUMER_TIMEOUT" envDefault:"10s"`
+
+	MaxMessageSize   int64           `env:"SIGNAL_MAX_MESSAGE_SIZE" envDefault:"65536"`
+	RateLimits       map[string]Rate `env:"SIGNAL_RATE_LIMITS" envDefault:"default:10/20,candidate:50/100,offer:5/10,answer:5/10"`
+	RateLimitStrikes int             `env:"SIGNAL_RATE_LIMIT_STRIKES" envDefault:"20"`
+
+	// ChatMaxLength limits chat message text, in characters
+	ChatMaxLength int `env:"SIGNAL_CHAT_MAX_LENGTH" envDefault:"2000"`
+}
+
+// Rate is a token bucket setting written as "<per second>/<burst>"
+type Rate struct {
+	PerSecond float64
+	Burst     int
+}
+
+func (r *Rate) UnmarshalText(text []byte) error {
+	perSecond, burst, ok := strings.Cut(string(text), "/")
+	if !ok {
+		return fmt.Errorf("rate %q must be <per second>/<burst>", text)
+	}
+
+	var err error
+	if r.PerSecond, err = strconv.ParseFloat(perSecond, 64); err != nil {
+		return fmt.Errorf("rate %q: %w", text, err)
+	}
+	if r.Burst, err = strconv.Atoi(burst); err != nil {
+		return fmt.Errorf("rate %q: %w", text, err)
+	}
+
+	return nil
+}
+
+type Bus struct {
+	Type              string        `env:"BUS_TYPE" envDefault:"local"`
+	NodeID            string        `env:"BUS_NODE_ID" envDefault:""`
+	ListenAddr        string        `env:"BUS_LISTEN_ADDR" envDefault:":7946"`
+	Peers             []string      `env:"BUS_PEERS" envSeparator:","`
+	Secret            string        `env:"BUS_SECRET" envDefault:""`
+	HeartbeatInterval time.Duration `env:"BUS_HEARTBEAT_INTERVAL" envDefault:"5s"`
+	NodeTimeout       time.Duration `env:"BUS_NODE_TIMEOUT" envDefault:"15s"`
 }
 
 func NewFromEnv() (*Config, error) {
@@ -61,5 +136,47 @@ func NewFromEnv() (*Config, error) {
 		return nil, fmt.Errorf("parsing environment config: %w", err)
 	}
 
+	if err := cfg.Signaling.validate(); err != nil {
+		return nil, fmt.Errorf("invalid signaling config: %w", err)
+	}
+
+	if err := cfg.Bus.validate(); err != nil {
+		return nil, fmt.Errorf("invalid bus config: %w", err)
+	}
+
 	return &cfg, nil
 }
+
+// minSendBuffer leaves room for a burst of SDP and presence messages, smaller queue overflows on every join
+const minSendBuffer = 16
+
+// validate checks keepalive timings, a peer must have a chance to answer ping before its read deadline passes,
+// and that send queue is large enough to be useful
+func (s *Signaling) validate() error {
+	if s.PingInterval <= 0 {
+		return fmt.Errorf("SIGNAL_PING_INTERVAL must be positive, got %s", s.PingInterval)
+	}
+	if s.PongTimeout <= s.PingInterval {
+		return fmt.Errorf("SIGNAL_PONG_TIMEOUT %s must be greater than SIGNAL_PING_INTERVAL %s", s.PongTimeout, s.PingInterval)
+	}
+	if s.WriteTimeout <= 0 {
+		return fmt.Errorf("SIGNAL_WRITE_TIMEOUT must be positive, got %s", s.WriteTimeout)
+	}
+	if s.SendBuffer < minSendBuffer {
+		return fmt.Errorf("SIGNAL_SEND_BUFFER must be at least %d, got %d", minSendBuffer, s.SendBuffer)
+	}
+
+	return nil
+}
+
+// validate checks cluster timings, a node has to miss more than one heartbeat before its peers drop it
+func (b *Bus) validate() error {
+	if b.HeartbeatInterval <= 0 {
+		return fmt.Errorf("BUS_HEARTBEAT_INTERVAL must be positive, got %s", b.HeartbeatInterval)
+	}
+	if b.NodeTimeout <= b.HeartbeatInterval {
+		return fmt.Errorf("BUS_NODE_TIMEOUT %s must be greater than BUS_HEARTBEAT_INTERVAL %s", b.NodeTimeout, b.HeartbeatInterval)
+	}
+
+	return nil
+}
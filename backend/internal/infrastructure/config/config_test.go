@@ -0,0 +1,60 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSignalingValidate(t *testing.T) {
+	valid := Signaling{PingInterval: 25 * time.Second, PongTimeout: 60 * time.Second, WriteTimeout: 10 * time.Second, SendBuffer: 256}
+
+	tests := []struct {
+		name    string
+		modify  func(s *Signaling)
+		wantErr bool
+	}{
+		{"defaults", func(s *Signaling) {}, false},
+		{"zero ping interval", func(s *Signaling) { s.PingInterval = 0 }, true},
+		{"negative ping interval", func(s *Signaling) { s.PingInterval = -time.Second }, true},
+		{"pong timeout equal to ping interval", func(s *Signaling) { s.PongTimeout = s.PingInterval }, true},
+		{"pong timeout below ping interval", func(s *Signaling) { s.PongTimeout = time.Second }, true},
+		{"zero write timeout", func(s *Signaling) { s.WriteTimeout = 0 }, true},
+		{"zero send buffer", func(s *Signaling) { s.SendBuffer = 0 }, true},
+		{"send buffer below minimum", func(s *Signaling) { s.SendBuffer = minSendBuffer - 1 }, true},
+		{"minimal send buffer", func(s *Signaling) { s.SendBuffer = minSendBuffer }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := valid
+			tt.modify(&s)
+			if err := s.validate(); (err != nil) != tt.wantErr {
+				t.Fatalf("validate() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBusValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		heartbeat time.Duration
+		timeout   time.Duration
+		wantErr   bool
+	}{
+		{"defaults", 5 * time.Second, 15 * time.Second, false},
+		{"zero heartbeat", 0, 15 * time.Second, true},
+		{"negative heartbeat", -time.Second, 15 * time.Second, true},
+		{"timeout equal to heartbeat", 5 * time.Second, 5 * time.Second, true},
+		{"timeout below heartbeat", 5 * time.Second, time.Second, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := Bus{HeartbeatInterval: tt.heartbeat, NodeTimeout: tt.timeout}
+			if err := b.validate(); (err != nil) != tt.wantErr {
+				t.Fatalf("validate() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -62,6 +62,36 @@ func (f *StorageFactory) CreateRefreshTokenRepository(ctx context.Context) repos
 	return mem.NewRefreshTokenRepository(ctx)
 }
 
+// CreateSignalTicketRepository creates storage of redeemed signaling tickets based on storage type
+func (f *StorageFactory) CreateSignalTicketRepository(ctx context.Context) repositories.SignalTicketRepositoryInterface {
+	if f.storageType == TypeMaria {
+		return db.NewMariaDBSignalTicketRepository(ctx, f.db.GetDB())
+	}
+
+	// Default to in-memory storage
+	return mem.NewSignalTicketRepository(ctx)
+}
+
+// CreateChatRepository creates a chat repository based on storage type
+func (f *StorageFactory) CreateChatRepository() repositories.ChatRepositoryInterface {
+	if f.storageType == TypeMaria {
+		return db.NewMariaDBChatRepository(f.db.GetDB())
+	}
+
+	// Default to in-memory storage
+	return mem.NewChatRepository()
+}
+
+// CreateCallRepository creates a call history repository based on storage type
+func (f *StorageFactory) CreateCallRepository() repositories.CallRepositoryInterface {
+	if f.storageType == TypeMaria {
+		return db.NewMariaDBCallRepository(f.db.GetDB())
+	}
+
+	// Default to in-memory storage
+	return mem.NewCallRepository()
+}
+
 // Close closes the database connection if using MariaDB
 func (f *StorageFactory) Close() error {
 	if f.db != nil {
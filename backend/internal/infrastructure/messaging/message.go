@@ -0,0 +1,298 @@
+package messaging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// ProtocolVersion current version of signaling envelope
+const ProtocolVersion = 1
+
+// Client-sent message types
+const (
+	TypeHello     = "hello"
+	TypeOffer     = "offer"
+	TypeAnswer    = "answer"
+	TypeCandidate = "candidate"
+	TypeEndCall   = "endCall"
+	TypePing      = "ping"
+	TypeAuth      = "auth"
+	TypeChat      = "chat"
+	// Host-only commands
+	TypeKick        = "kick"
+	TypeMuteRequest = "mute-request"
+	TypeEndMeeting  = "end-meeting"
+	TypeAdmit       = "admit"
+	TypeDeny        = "deny"
+	// Participant signals
+	TypeRaiseHand = "raise-hand"
+	TypeLowerHand = "lower-hand"
+	TypeReaction  = "reaction"
+)
+
+// Server-sent message types
+const (
+	TypeError      = "error"
+	TypeRoster     = "roster"
+	TypePeerJoined = "peer-joined"
+	TypePeerLeft   = "peer-left"
+	TypeSession    = "session"
+	TypeResync     = "resync"
+	// TypeReauthRequired asks client to send auth with a fresh token before the current one expires
+	TypeReauthRequired = "reauth-required"
+	TypeAuthAccepted   = "auth-accepted"
+	// TypeKnock tells the host somebody waits in the lobby, TypeKnockResolved that the request is no longer pending
+	TypeKnock         = "knock"
+	TypeKnockResolved = "knock-resolved"
+	// TypeHands carries the raised-hand queue after every change
+	TypeHands = "hands"
+)
+
+var (
+	ErrMalformedMessage   = errors.New("malformed message")
+	ErrUnsupportedVersion = errors.New("unsupported protocol version")
+	ErrUnknownType        = errors.New("unknown message type")
+	ErrPayloadRequired    = errors.New("payload required")
+	ErrRecipientNotFound  = errors.New("recipient not found")
+	ErrRateLimited        = errors.New("rate limit exceeded")
+	ErrAuthRequired       = errors.New("authentication required")
+	ErrAuthMismatch       = errors.New("token issued for another user or room")
+	ErrInvalidChat        = errors.New("chat message is empty or too long")
+	ErrChatNotSaved       = errors.New("message not saved")
+	ErrForbidden          = errors.New("only the room host can do this")
+	ErrTargetRequired     = errors.New("target user required")
+	ErrInvalidReaction    = errors.New("unsupported reaction")
+)
+
+// payloadRequired marks client message types and whether they must carry payload
+var payloadRequired = map[string]bool{
+	TypeHello:       false,
+	TypeOffer:       true,
+	TypeAnswer:      true,
+	TypeCandidate:   true,
+	TypeEndCall:     false,
+	TypePing:        false,
+	TypeAuth:        true,
+	TypeChat:        true,
+	TypeKick:        false,
+	TypeMuteRequest: false,
+	TypeEndMeeting:  false,
+	TypeAdmit:       true,
+	TypeDeny:        true,
+	TypeRaiseHand:   false,
+	TypeLowerHand:   false,
+	TypeReaction:    true,
+}
+
+// Reactions lists emoji accepted in reaction messages
+var Reactions = map[string]bool{
+	"👍":  true,
+	"👏":  true,
+	"😂":  true,
+	"❤️": true,
+	"🎉":  true,
+	"😮":  true,
+}
+
+// Envelope is a signaling message. From fields are always stamped by the server.
+// To delivers message to all devices of the user, ToSession to a single device; without both message is broadcast to the room
+type Envelope struct {
+	Version     int             `json:"v"`
+	Type        string          `json:"type"`
+	From        string          `json:"from,omitempty"`
+	FromName    string          `json:"from_name,omitempty"`
+	FromSession string          `json:"from_session,omitempty"`
+	To          string          `json:"to,omitempty"`
+	ToSession   string          `json:"to_session,omitempty"`
+	Payload     json.RawMessage `json:"payload,omitempty"`
+}
+
+// Peer is a single device of a room participant
+type Peer struct {
+	UserID    string `json:"user_id"`
+	Username  string `json:"username"`
+	SessionID string `json:"session_id"`
+	// JoinedAt is unix milliseconds when the device entered the room
+	JoinedAt int64 `json:"joined_at,omitempty"`
+}
+
+type AuthPayload struct {
+	Token string `json:"token"`
+}
+
+// ChatPayload is sent by client with text only, server adds ID and time of the stored message
+type ChatPayload struct {
+	ID     int64  `json:"id,omitempty"`
+	Text   string `json:"text"`
+	SentAt int64  `json:"sent_at,omitempty"`
+}
+
+// ModerationPayload is an optional payload of host commands. Kind tells which track mute-request is about: audio or video
+type ModerationPayload struct {
+	Reason    string `json:"reason,omitempty"`
+	Kind      string `json:"kind,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+type KnockPayload struct {
+	RequestID string `json:"request_id"`
+	UserID    string `json:"user_id"`
+	Username  string `json:"username"`
+	Status    string `json:"status"`
+}
+
+// AuthStatePayload tells when the current token of the connection expires
+type AuthStatePayload struct {
+	ExpiresAt int64 `json:"expires_at"`
+}
+
+type SessionPayload struct {
+	SessionID string `json:"session_id"`
+	Resumed   bool   `json:"resumed"`
+}
+
+// ResyncPayload tells client that messages were dropped and it should renegotiate with peers
+type ResyncPayload struct {
+	Dropped int `json:"dropped"`
+}
+
+type RosterPayload struct {
+	RoomID   string `json:"room_id"`
+	Capacity int    `json:"capacity"`
+	Peers    []Peer `json:"peers"`
+	Hands    []Hand `json:"hands"`
+}
+
+// Hand is a raised hand of a participant, queue is ordered by RaisedAt
+type Hand struct {
+	UserID   string `json:"user_id"`
+	Username string `json:"username"`
+	RaisedAt int64  `json:"raised_at"`
+}
+
+type HandsPayload struct {
+	Hands []Hand `json:"hands"`
+}
+
+type ReactionPayload struct {
+	Emoji string `json:"emoji"`
+}
+
+type ErrorPayload struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Type    string `json:"type,omitempty"`
+}
+
+// Decode parses and validates a client message
+func Decode(data []byte) (*Envelope, error) {
+	var env Envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformedMessage, err)
+	}
+
+	if err := env.Validate(); err != nil {
+		return &env, err
+	}
+
+	return &env, nil
+}
+
+func (e *Envelope) Validate() error {
+	if e.Version == 0 {
+		e.Version = ProtocolVersion
+	}
+
+	if e.Version != ProtocolVersion {
+		return fmt.Errorf("%w: %d", ErrUnsupportedVersion, e.Version)
+	}
+
+	needPayload, ok := payloadRequired[e.Type]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownType, e.Type)
+	}
+
+	payload := bytes.TrimSpace(e.Payload)
+	if needPayload && (len(payload) == 0 || bytes.Equal(payload, []byte("null"))) {
+		return fmt.Errorf("%w for %s", ErrPayloadRequired, e.Type)
+	}
+
+	if len(payload) > 0 && !json.Valid(payload) {
+		return fmt.Errorf("%w: invalid payload", ErrMalformedMessage)
+	}
+
+	return nil
+}
+
+func (e *Envelope) Encode() ([]byte, error) {
+	return json.Marshal(e)
+}
+
+// NewEnvelope creates server-sent message
+func NewEnvelope(msgType string, payload any) *Envelope {
+	env := &Envelope{
+		Version: ProtocolVersion,
+		Type:    msgType,
+	}
+
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			log.Printf("failed to marshal %s payload: %v", msgType, err)
+		} else {
+			env.Payload = data
+		}
+	}
+
+	return env
+}
+
+// Frame builds encoded server-sent message
+func Frame(msgType string, payload any) []byte {
+	data, err := NewEnvelope(msgType, payload).Encode()
+	if err != nil {
+		log.Printf("failed to encode %s message: %v", msgType, err)
+	}
+
+	return data
+}
+
+// ErrorFrame builds an error message sent back to the client
+func ErrorFrame(err error, msgType string) []byte {
+	code := "internal"
+	switch {
+	case errors.Is(err, ErrMalformedMessage):
+		code = "malformed"
+	case errors.Is(err, ErrUnsupportedVersion):
+		code = "unsupported_version"
+	case errors.Is(err, ErrUnknownType):
+		code = "unknown_type"
+	case errors.Is(err, ErrPayloadRequired):
+		code = "payload_required"
+	case errors.Is(err, ErrRecipientNotFound):
+		code = "recipient_not_found"
+	case errors.Is(err, ErrRateLimited):
+		code = "rate_limited"
+	case errors.Is(err, ErrAuthRequired):
+		code = "auth_required"
+	case errors.Is(err, ErrAuthMismatch):
+		code = "auth_mismatch"
+	case errors.Is(err, ErrInvalidChat):
+		code = "invalid_chat"
+	case errors.Is(err, ErrForbidden):
+		code = "forbidden"
+	case errors.Is(err, ErrTargetRequired):
+		code = "target_required"
+	case errors.Is(err, ErrInvalidReaction):
+		code = "invalid_reaction"
+	}
+
+	return Frame(TypeError, ErrorPayload{
+		Code:    code,
+		Message: err.Error(),
+		Type:    msgType,
+	})
+}
@@ -0,0 +1,104 @@
+package messaging
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// originChecker allows websocket upgrade from configured origins.
+// Entry is either "*", exact origin like "https://call.example.com" or wildcard subdomain like "https://*.example.com".
+// Port is part of the origin: entry without port allows only the default port of its scheme, "https://*.example.com:8443" allows 8443.
+// Without entries only same-origin requests are accepted
+type originChecker struct {
+	allowed []string
+}
+
+func newOriginChecker(allowed []string) *originChecker {
+	oc := &originChecker{}
+	for _, origin := range allowed {
+		origin = strings.ToLower(strings.TrimRight(strings.TrimSpace(origin), "/"))
+		if origin != "" {
+			oc.allowed = append(oc.allowed, origin)
+		}
+	}
+
+	return oc
+}
+
+func (oc *originChecker) check(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		// non-browser clients do not send Origin
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		log.Printf("ws upgrade rejected: malformed origin %q from %s", origin, r.RemoteAddr)
+		return false
+	}
+
+	if len(oc.allowed) == 0 {
+		if strings.EqualFold(u.Host, r.Host) {
+			return true
+		}
+	} else if oc.matches(strings.ToLower(u.Scheme), strings.ToLower(u.Host)) {
+		return true
+	}
+
+	log.Printf("ws upgrade rejected: origin %q is not allowed (%s)", origin, r.RemoteAddr)
+
+	return false
+}
+
+func (oc *originChecker) matches(scheme, host string) bool {
+	hostname, port := splitOriginHost(scheme, host)
+
+	for _, allowed := range oc.allowed {
+		if allowed == "*" {
+			return true
+		}
+
+		allowedScheme, allowedHost, ok := strings.Cut(allowed, "://")
+		if !ok || allowedScheme != scheme {
+			continue
+		}
+
+		allowedHostname, allowedPort := splitOriginHost(scheme, allowedHost)
+		if allowedPort != port {
+			continue
+		}
+
+		if suffix, ok := strings.CutPrefix(allowedHostname, "*."); ok {
+			if strings.HasSuffix(hostname, "."+suffix) {
+				return true
+			}
+			continue
+		}
+
+		if allowedHostname == hostname {
+			return true
+		}
+	}
+
+	return false
+}
+
+// splitOriginHost separates port from host, default port of the scheme is filled in when omitted
+func splitOriginHost(scheme, host string) (string, string) {
+	if hostname, port, err := net.SplitHostPort(host); err == nil {
+		return hostname, port
+	}
+
+	switch scheme {
+	case "https", "wss":
+		return host, "443"
+	case "http", "ws":
+		return host, "80"
+	default:
+		return host, ""
+	}
+}
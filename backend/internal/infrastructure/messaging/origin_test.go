@@ -0,0 +1,48 @@
+package messaging
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOriginChecker(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		origin  string
+		host    string
+		want    bool
+	}{
+		{"no origin header", nil, "", "call.example.com", true},
+		{"same origin", nil, "https://call.example.com", "call.example.com", true},
+		{"cross origin without entries", nil, "https://evil.com", "call.example.com", false},
+		{"malformed origin", []string{"*"}, "://", "call.example.com", false},
+		{"any origin", []string{"*"}, "https://evil.com:8443", "call.example.com", true},
+		{"exact origin", []string{"https://app.example.com"}, "https://app.example.com", "api.example.com", true},
+		{"exact origin default port written out", []string{"https://app.example.com"}, "https://app.example.com:443", "api.example.com", true},
+		{"exact origin other port", []string{"https://app.example.com"}, "https://app.example.com:8443", "api.example.com", false},
+		{"exact origin other scheme", []string{"https://app.example.com"}, "http://app.example.com", "api.example.com", false},
+		{"entry with trailing slash and case", []string{" HTTPS://App.Example.com/ "}, "https://app.example.com", "api.example.com", true},
+		{"wildcard subdomain", []string{"https://*.example.com"}, "https://a.b.example.com", "api.example.com", true},
+		{"wildcard does not match apex", []string{"https://*.example.com"}, "https://example.com", "api.example.com", false},
+		{"wildcard does not match suffix lookalike", []string{"https://*.example.com"}, "https://evilexample.com", "api.example.com", false},
+		{"wildcard other port", []string{"https://*.example.com"}, "https://evil.example.com:8443", "api.example.com", false},
+		{"wildcard with port", []string{"https://*.example.com:8443"}, "https://app.example.com:8443", "api.example.com", true},
+		{"wildcard with port default port", []string{"https://*.example.com:8443"}, "https://app.example.com", "api.example.com", false},
+		{"exact origin with port", []string{"http://localhost:3000"}, "http://localhost:3000", "localhost:8080", true},
+		{"exact origin with port other port", []string{"http://localhost:3000"}, "http://localhost:3001", "localhost:8080", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "http://"+tt.host+"/ws", nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+
+			if got := newOriginChecker(tt.allowed).check(r); got != tt.want {
+				t.Fatalf("check(%q) with %v = %v, want %v", tt.origin, tt.allowed, got, tt.want)
+			}
+		})
+	}
+}
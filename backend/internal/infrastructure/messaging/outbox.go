@@ -0,0 +1,146 @@
+package messaging
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// Message priorities, lower ones are dropped first when client can not keep up
+const (
+	priorityLow = iota
+	priorityNormal
+	// prioritySDP messages are never dropped, peer connection can not be set up without them
+	prioritySDP
+)
+
+var messagePriority = map[string]int{
+	TypeCandidate: priorityLow,
+	TypeReaction:  priorityLow,
+	TypeOffer:     prioritySDP,
+	TypeAnswer:    prioritySDP,
+}
+
+// queuedMessage keeps priority next to the frame, so that eviction does not decode queued frames
+type queuedMessage struct {
+	data     []byte
+	priority int
+}
+
+// outbox is an ordered queue of outgoing messages with bounded size.
+// When full, incoming low priority message is dropped, normal one evicts the oldest low priority message
+// and SDP evicts the oldest message that is not SDP. Queue full of SDP can not take more, client has to be disconnected.
+// Client is told about dropped messages with a resync event once the queue drains
+type outbox struct {
+	queue     []queuedMessage
+	notify    chan struct{}
+	size      int
+	dropped   int
+	slowSince time.Time
+	closed    bool
+	mu        sync.Mutex
+}
+
+func newOutbox(size int) *outbox {
+	return &outbox{
+		notify: make(chan struct{}, 1),
+		size:   size,
+	}
+}
+
+// push enqueues message and reports for how long the queue is overflowing.
+// lost is set when SDP message could not be queued
+func (o *outbox) push(msg []byte) (slowFor time.Duration, lost bool) {
+	priority := priorityOf(msg)
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.closed {
+		return 0, false
+	}
+
+	if len(o.queue) >= o.size {
+		if o.slowSince.IsZero() {
+			o.slowSince = time.Now()
+		}
+		o.dropped++
+
+		if !o.evict(priority) {
+			return time.Since(o.slowSince), priority == prioritySDP
+		}
+	}
+
+	o.queue = append(o.queue, queuedMessage{data: msg, priority: priority})
+
+	select {
+	case o.notify <- struct{}{}:
+	default:
+	}
+
+	if o.slowSince.IsZero() {
+		return 0, false
+	}
+
+	return time.Since(o.slowSince), false
+}
+
+// evict frees a slot for a message of the priority, dropping the oldest message of the lowest priority below it
+func (o *outbox) evict(priority int) bool {
+	for lower := priorityLow; lower < priority; lower++ {
+		for i, queued := range o.queue {
+			if queued.priority == lower {
+				o.queue = append(o.queue[:i], o.queue[i+1:]...)
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// drain takes all queued messages, followed by resync event if something was dropped
+func (o *outbox) drain() [][]byte {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	msgs := make([][]byte, 0, len(o.queue)+1)
+	for _, queued := range o.queue {
+		msgs = append(msgs, queued.data)
+	}
+	o.queue = nil
+	o.slowSince = time.Time{}
+
+	if o.dropped > 0 {
+		msgs = append(msgs, Frame(TypeResync, ResyncPayload{Dropped: o.dropped}))
+		o.dropped = 0
+	}
+
+	return msgs
+}
+
+func (o *outbox) close() bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.closed {
+		return false
+	}
+	o.closed = true
+	close(o.notify)
+
+	return true
+}
+
+func priorityOf(msg []byte) int {
+	var head struct {
+		Type string `json:"type"`
+	}
+	_ = json.Unmarshal(msg, &head)
+
+	if priority, ok := messagePriority[head.Type]; ok {
+		return priority
+	}
+
+	return priorityNormal
+}
@@ -0,0 +1,90 @@
+package messaging
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func frameTypes(t *testing.T, msgs [][]byte) []string {
+	t.Helper()
+
+	types := make([]string, 0, len(msgs))
+	for _, msg := range msgs {
+		var env Envelope
+		if err := json.Unmarshal(msg, &env); err != nil {
+			t.Fatalf("malformed frame %s: %v", msg, err)
+		}
+		types = append(types, env.Type)
+	}
+
+	return types
+}
+
+func TestOutboxOverflow(t *testing.T) {
+	tests := []struct {
+		name     string
+		queued   []string
+		push     string
+		want     []string
+		wantLost bool
+	}{
+		{"low dropped", []string{TypeCandidate, TypeHello}, TypeReaction, []string{TypeCandidate, TypeHello, TypeResync}, false},
+		{"normal evicts oldest low", []string{TypeHello, TypeCandidate, TypeReaction}, TypeHello, []string{TypeHello, TypeReaction, TypeHello, TypeResync}, false},
+		{"normal dropped without low", []string{TypeHello, TypeOffer}, TypeChat, []string{TypeHello, TypeOffer, TypeResync}, false},
+		{"sdp evicts low before normal", []string{TypeHello, TypeCandidate}, TypeOffer, []string{TypeHello, TypeOffer, TypeResync}, false},
+		{"sdp evicts oldest normal", []string{TypeOffer, TypeHello, TypeChat}, TypeAnswer, []string{TypeOffer, TypeChat, TypeAnswer, TypeResync}, false},
+		{"sdp lost when queue holds only sdp", []string{TypeOffer, TypeAnswer}, TypeOffer, []string{TypeOffer, TypeAnswer, TypeResync}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := newOutbox(len(tt.queued))
+			for _, msgType := range tt.queued {
+				if slowFor, lost := o.push(Frame(msgType, nil)); slowFor != 0 || lost {
+					t.Fatalf("push of %s into free queue reported overflow", msgType)
+				}
+			}
+
+			_, lost := o.push(Frame(tt.push, nil))
+			if lost != tt.wantLost {
+				t.Fatalf("lost = %v, want %v", lost, tt.wantLost)
+			}
+
+			if got := frameTypes(t, o.drain()); !slices.Equal(got, tt.want) {
+				t.Fatalf("drained %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOutboxDrainResetsOverflow(t *testing.T) {
+	o := newOutbox(1)
+	o.push(Frame(TypeHello, nil))
+	if slowFor, _ := o.push(Frame(TypeChat, nil)); slowFor == 0 {
+		t.Fatal("overflowing push reported no slowdown")
+	}
+
+	o.drain()
+
+	if slowFor, _ := o.push(Frame(TypeHello, nil)); slowFor != 0 {
+		t.Fatalf("push after drain reported slowdown %v", slowFor)
+	}
+	if got := frameTypes(t, o.drain()); !slices.Equal(got, []string{TypeHello}) {
+		t.Fatalf("drained %v, want only hello", got)
+	}
+}
+
+func TestOutboxClosed(t *testing.T) {
+	o := newOutbox(1)
+	if !o.close() {
+		t.Fatal("first close reported already closed")
+	}
+	if o.close() {
+		t.Fatal("second close reported closing")
+	}
+
+	if slowFor, lost := o.push(Frame(TypeOffer, nil)); slowFor != 0 || lost {
+		t.Fatal("push after close reported overflow")
+	}
+}
@@ -0,0 +1,80 @@
+package messaging
+
+import (
+	"time"
+	"videocall/internal/infrastructure/config"
+)
+
+// defaultRateKey is used for message types without own limit
+const defaultRateKey = "default"
+
+// strikeReset quiet period after which rate limit violations are forgotten
+const strikeReset = 10 * time.Second
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+	rate   config.Rate
+}
+
+func (b *tokenBucket) allow(now time.Time) bool {
+	b.tokens += now.Sub(b.last).Seconds() * b.rate.PerSecond
+	if b.tokens > float64(b.rate.Burst) {
+		b.tokens = float64(b.rate.Burst)
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+
+	return true
+}
+
+// rateLimiter keeps token buckets of a single client by message type. It is used from ReadPump only
+type rateLimiter struct {
+	rates      map[string]config.Rate
+	buckets    map[string]*tokenBucket
+	strikes    int
+	lastStrike time.Time
+}
+
+func newRateLimiter(rates map[string]config.Rate) *rateLimiter {
+	return &rateLimiter{
+		rates:   rates,
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// allow reports whether message of given type fits the limit and how many violations happened recently
+func (l *rateLimiter) allow(msgType string) (bool, int) {
+	key := msgType
+	rate, ok := l.rates[key]
+	if !ok {
+		key = defaultRateKey
+		rate, ok = l.rates[key]
+	}
+	if !ok {
+		return true, 0
+	}
+
+	now := time.Now()
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(rate.Burst), last: now, rate: rate}
+		l.buckets[key] = b
+	}
+
+	if b.allow(now) {
+		return true, l.strikes
+	}
+
+	if now.Sub(l.lastStrike) > strikeReset {
+		l.strikes = 0
+	}
+	l.strikes++
+	l.lastStrike = now
+
+	return false, l.strikes
+}
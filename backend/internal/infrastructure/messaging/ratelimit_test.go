@@ -0,0 +1,73 @@
+package messaging
+
+import (
+	"testing"
+	"time"
+	"videocall/internal/infrastructure/config"
+)
+
+func TestTokenBucket(t *testing.T) {
+	start := time.Now()
+	b := &tokenBucket{tokens: 2, last: start, rate: config.Rate{PerSecond: 1, Burst: 2}}
+
+	for i := 0; i < 2; i++ {
+		if !b.allow(start) {
+			t.Fatalf("message %d within burst refused", i+1)
+		}
+	}
+	if b.allow(start) {
+		t.Fatal("message over burst allowed")
+	}
+
+	if b.allow(start.Add(500 * time.Millisecond)) {
+		t.Fatal("message allowed before a token was refilled")
+	}
+	if !b.allow(start.Add(time.Second)) {
+		t.Fatal("message refused after a token was refilled")
+	}
+
+	// idle bucket refills up to burst only
+	later := start.Add(time.Hour)
+	for i := 0; i < 2; i++ {
+		if !b.allow(later) {
+			t.Fatalf("message %d after idle period refused", i+1)
+		}
+	}
+	if b.allow(later) {
+		t.Fatal("idle bucket refilled over burst")
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	l := newRateLimiter(map[string]config.Rate{
+		defaultRateKey: {PerSecond: 0.001, Burst: 1},
+		TypeCandidate:  {PerSecond: 0.001, Burst: 3},
+	})
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.allow(TypeCandidate); !ok {
+			t.Fatalf("candidate %d within own limit refused", i+1)
+		}
+	}
+	if ok, strikes := l.allow(TypeCandidate); ok || strikes != 1 {
+		t.Fatalf("candidate over limit: allowed %v with %d strikes, want refused with 1", ok, strikes)
+	}
+
+	// types without own limit share the default bucket
+	if ok, _ := l.allow(TypeHello); !ok {
+		t.Fatal("first hello refused")
+	}
+	if ok, strikes := l.allow(TypeChat); ok || strikes != 2 {
+		t.Fatalf("chat after hello: allowed %v with %d strikes, want refused with 2", ok, strikes)
+	}
+}
+
+func TestRateLimiterUnlimited(t *testing.T) {
+	l := newRateLimiter(map[string]config.Rate{TypeCandidate: {PerSecond: 1, Burst: 1}})
+
+	for i := 0; i < 100; i++ {
+		if ok, _ := l.allow(TypeHello); !ok {
+			t.Fatal("message without limit refused")
+		}
+	}
+}
@@ -2,51 +2,239 @@ package messaging
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"strings"
 	"sync"
+	"time"
 	"videocall/internal/infrastructure/auth"
+	"videocall/internal/infrastructure/config"
 
 	"github.com/gorilla/websocket"
 )
 
 const BufferSize = 256
 
+// Subprotocol is selected by the server during upgrade. Client may offer jwt as a second
+// subprotocol prefixed with SubprotocolTokenPrefix to keep it out of the URL
+const (
+	Subprotocol            = "videocall.v1"
+	SubprotocolTokenPrefix = "jwt."
+)
+
+// Application close codes, see RFC 6455 section 7.4.2
+const (
+	CloseRoomFull     = 4001
+	CloseSlowConsumer = 4002
+	CloseRateLimited  = 4003
+	CloseAuthRequired = 4004
+	CloseNotAdmitted  = 4005
+	CloseAuthExpired  = 4006
+	CloseKicked       = 4007
+	CloseRoomEnded    = 4008
+)
+
 type Client struct {
-	UserID   string
-	Username string
-	RoomID   string
-	conn     *websocket.Conn
-	send     chan []byte
-	once     sync.Once
+	UserID    string
+	Username  string
+	RoomID    string
+	SessionID string
+	cfg       config.Signaling
+	conn      *websocket.Conn
+	out       *outbox
+	limiter   *rateLimiter
+	validate  TokenValidator
+	// renewed is signalled when client sends a fresh token
+	renewed chan struct{}
+	expiry  time.Time
+	authMu  sync.Mutex
+	// slowClose makes a flood of overflowing sends close the connection only once
+	slowClose sync.Once
 }
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  BufferSize,
-	WriteBufferSize: BufferSize,
-	WriteBufferPool: &sync.Pool{},
+var writeBufferPool = &sync.Pool{}
+
+func newUpgrader(cfg config.Signaling) *websocket.Upgrader {
+	return &websocket.Upgrader{
+		ReadBufferSize:  BufferSize,
+		WriteBufferSize: BufferSize,
+		WriteBufferPool: writeBufferPool,
+		CheckOrigin:     newOriginChecker(cfg.AllowedOrigins).check,
+		Subprotocols:    []string{Subprotocol},
+	}
 }
 
-func NewClient(w http.ResponseWriter, r *http.Request, claims *auth.Claims) (*Client, error) {
-	conn, err := upgrader.Upgrade(w, r, nil)
+// TokenValidator parses jwt sent by the client
+type TokenValidator func(token string) (*auth.Claims, error)
+
+// NewClient upgrades connection. Without claims client has to authenticate in-band with AwaitAuth before use.
+// validate is used for the in-band auth and for tokens renewing an expiring session
+func NewClient(w http.ResponseWriter, r *http.Request, claims *auth.Claims, validate TokenValidator, cfg config.Signaling) (*Client, error) {
+	conn, err := newUpgrader(cfg).Upgrade(w, r, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Client{
-		UserID:   claims.UserID,
-		Username: claims.Username,
-		RoomID:   claims.RoomID,
+	c := &Client{
+		cfg:      cfg,
 		conn:     conn,
-		send:     make(chan []byte, BufferSize),
-	}, nil
+		out:      newOutbox(cfg.SendBuffer),
+		limiter:  newRateLimiter(cfg.RateLimits),
+		validate: validate,
+		renewed:  make(chan struct{}, 1),
+	}
+
+	if claims != nil {
+		c.setIdentity(claims)
+	}
+
+	return c, nil
+}
+
+// TokenFromSubprotocol returns jwt offered in Sec-WebSocket-Protocol header
+func TokenFromSubprotocol(r *http.Request) string {
+	for _, protocol := range websocket.Subprotocols(r) {
+		if token, ok := strings.CutPrefix(protocol, SubprotocolTokenPrefix); ok {
+			return token
+		}
+	}
+
+	return ""
+}
+
+// AwaitAuth expects the first message to be auth within AuthTimeout and validates its token
+func (c *Client) AwaitAuth() (*auth.Claims, error) {
+	_ = c.conn.SetReadDeadline(time.Now().Add(c.cfg.AuthTimeout))
+
+	_, data, err := c.conn.ReadMessage()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrAuthRequired, err)
+	}
+
+	env, err := Decode(data)
+	if err != nil || env.Type != TypeAuth {
+		return nil, ErrAuthRequired
+	}
+
+	var payload AuthPayload
+	if err := json.Unmarshal(env.Payload, &payload); err != nil || payload.Token == "" {
+		return nil, ErrAuthRequired
+	}
+
+	claims, err := c.validate(payload.Token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrAuthRequired, err)
+	}
+	c.setIdentity(claims)
+
+	return claims, nil
+}
+
+func (c *Client) setIdentity(claims *auth.Claims) {
+	c.UserID = claims.UserID
+	c.Username = claims.Username
+	c.RoomID = claims.RoomID
+	c.setExpiry(claims)
+}
+
+// reauthenticate accepts a fresh token for the same user and room and extends the session
+func (c *Client) reauthenticate(env *Envelope) error {
+	var payload AuthPayload
+	if err := json.Unmarshal(env.Payload, &payload); err != nil || payload.Token == "" {
+		return ErrAuthRequired
+	}
+
+	claims, err := c.validate(payload.Token)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrAuthRequired, err)
+	}
+
+	if claims.UserID != c.UserID || claims.RoomID != c.RoomID {
+		return ErrAuthMismatch
+	}
+
+	c.setExpiry(claims)
+
+	select {
+	case c.renewed <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+func (c *Client) setExpiry(claims *auth.Claims) {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+
+	c.expiry = time.Time{}
+	if claims.ExpiresAt != nil {
+		c.expiry = claims.ExpiresAt.Time
+	}
+}
+
+func (c *Client) tokenExpiry() time.Time {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+
+	return c.expiry
+}
+
+// watchExpiry asks client for a fresh token ReauthLead before expiry and closes connection if none arrives in time
+func (c *Client) watchExpiry(stop <-chan struct{}) {
+	warned := false
+
+	for {
+		expiry := c.tokenExpiry()
+		if expiry.IsZero() {
+			return
+		}
+
+		deadline := expiry
+		if !warned {
+			deadline = expiry.Add(-c.cfg.ReauthLead)
+		}
+		timer := time.NewTimer(time.Until(deadline))
+
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case <-c.renewed:
+			timer.Stop()
+			warned = false
+		case <-timer.C:
+			if !warned {
+				warned = true
+				c.Send(Frame(TypeReauthRequired, AuthStatePayload{ExpiresAt: expiry.Unix()}))
+				continue
+			}
+
+			log.Printf("closing %s (%s): token expired", c.Username, c.UserID)
+			c.CloseWithReason(CloseAuthExpired, "token expired")
+			return
+		}
+	}
 }
 
-func (c *Client) ReadPump(ctx context.Context, read chan<- []byte) {
+// ReadPump decodes incoming messages and passes valid ones to read. Malformed messages are answered with an error frame.
+// Client that sends neither messages nor pongs within PongTimeout is considered dead and pump exits
+func (c *Client) ReadPump(ctx context.Context, read chan<- *Envelope) {
 	defer func() {
 		close(read)
 	}()
 
+	c.conn.SetReadLimit(c.cfg.MaxMessageSize)
+	c.extendReadDeadline()
+	c.conn.SetPongHandler(func(string) error {
+		c.extendReadDeadline()
+		return nil
+	})
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -54,29 +242,102 @@ func (c *Client) ReadPump(ctx context.Context, read chan<- []byte) {
 		default:
 		}
 
-		_, msg, err := c.conn.ReadMessage()
+		_, data, err := c.conn.ReadMessage()
 		if err != nil {
+			var netErr net.Error
+			switch {
+			case errors.As(err, &netErr) && netErr.Timeout():
+				log.Printf("evicting unresponsive client %s (%s) from room %s", c.Username, c.UserID, c.RoomID)
+			case errors.Is(err, websocket.ErrReadLimit):
+				// gorilla already answered with 1009 close frame
+				log.Printf("closing %s (%s): message exceeds %d bytes", c.Username, c.UserID, c.cfg.MaxMessageSize)
+			}
 			return
 		}
+		c.extendReadDeadline()
+
+		env, err := Decode(data)
+
+		msgType := ""
+		if env != nil {
+			msgType = env.Type
+		}
+		if allowed, strikes := c.limiter.allow(msgType); !allowed {
+			if strikes >= c.cfg.RateLimitStrikes {
+				log.Printf("closing %s (%s): rate limit exceeded %d times", c.Username, c.UserID, strikes)
+				c.CloseWithReason(CloseRateLimited, "rate limit exceeded")
+				return
+			}
+			c.Send(ErrorFrame(ErrRateLimited, msgType))
+			continue
+		}
+
+		if err != nil {
+			log.Printf("rejected message from %s (%s): %v", c.Username, c.UserID, err)
+			c.Send(ErrorFrame(err, msgType))
+			continue
+		}
 
-		read <- msg
+		if env.Type == TypePing {
+			continue
+		}
+
+		// tokens are never relayed to peers
+		if env.Type == TypeAuth {
+			if err := c.reauthenticate(env); err != nil {
+				log.Printf("rejected token renewal from %s (%s): %v", c.Username, c.UserID, err)
+				c.Send(ErrorFrame(err, TypeAuth))
+				continue
+			}
+			c.Send(Frame(TypeAuthAccepted, AuthStatePayload{ExpiresAt: c.tokenExpiry().Unix()}))
+			continue
+		}
+
+		// sender identity always comes from jwt claims
+		env.From = c.UserID
+		env.FromName = c.Username
+		env.FromSession = c.SessionID
+
+		select {
+		case read <- env:
+		case <-ctx.Done():
+			return
+		}
 	}
 }
 
+// WritePump delivers queued messages and pings client every PingInterval. Connection is closed on write failure so ReadPump unblocks too
 func (c *Client) WritePump(ctx context.Context, finish chan<- struct{}) {
+	ticker := time.NewTicker(c.cfg.PingInterval)
+	stop := make(chan struct{})
+	go c.watchExpiry(stop)
+
 	defer func() {
+		close(stop)
+		ticker.Stop()
+		_ = c.conn.Close()
 		finish <- struct{}{}
 	}()
 
 	for {
 		select {
-		case msg, ok := <-c.send:
+		case _, ok := <-c.out.notify:
+			for _, msg := range c.out.drain() {
+				_ = c.conn.SetWriteDeadline(time.Now().Add(c.cfg.WriteTimeout))
+				if err := c.conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+					log.Println("write error:", err)
+					return
+				}
+			}
 			if !ok {
+				_ = c.conn.SetWriteDeadline(time.Now().Add(c.cfg.WriteTimeout))
 				_ = c.conn.WriteMessage(websocket.CloseMessage, []byte{})
 				return
 			}
-			if err := c.conn.WriteMessage(websocket.TextMessage, msg); err != nil {
-				log.Println("write error:", err)
+		case <-ticker.C:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(c.cfg.WriteTimeout))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				log.Printf("ping to %s (%s) failed: %v", c.Username, c.UserID, err)
 				return
 			}
 		case <-ctx.Done():
@@ -85,17 +346,41 @@ func (c *Client) WritePump(ctx context.Context, finish chan<- struct{}) {
 	}
 }
 
+// CloseWithReason sends close frame with application code and closes connection
+func (c *Client) CloseWithReason(code int, reason string) {
+	msg := websocket.FormatCloseMessage(code, reason)
+	_ = c.conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(c.cfg.WriteTimeout))
+	c.Close()
+}
+
 func (c *Client) Close() {
-	c.once.Do(func() {
-		close(c.send)
+	if c.out.close() {
 		_ = c.conn.Close()
-	})
+	}
 }
 
+// Send queues message for the client. Client that can not keep up longer than SlowConsumerTimeout is disconnected,
+// so is the one whose queue has no room for SDP
 func (c *Client) Send(msg []byte) {
-	select {
-	case c.send <- msg:
-	default:
-		log.Printf("send channel full, dropping message for user %s (%s)", c.Username, c.UserID)
+	slowFor, lost := c.out.push(msg)
+	if slowFor == 0 {
+		return
 	}
+
+	log.Printf("send queue full for user %s (%s), dropping messages", c.Username, c.UserID)
+
+	if lost || c.cfg.SlowConsumerTimeout > 0 && slowFor > c.cfg.SlowConsumerTimeout {
+		c.slowClose.Do(func() {
+			log.Printf("disconnecting slow consumer %s (%s) from room %s", c.Username, c.UserID, c.RoomID)
+			go c.CloseWithReason(CloseSlowConsumer, "slow consumer")
+		})
+	}
+}
+
+func (c *Client) Peer() Peer {
+	return Peer{UserID: c.UserID, Username: c.Username, SessionID: c.SessionID}
+}
+
+func (c *Client) extendReadDeadline() {
+	_ = c.conn.SetReadDeadline(time.Now().Add(c.cfg.PongTimeout))
 }
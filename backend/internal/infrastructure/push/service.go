@@ -110,6 +110,19 @@ func (s *Service) NotifyUserJoined(creatorUserID, joinerUsername, roomID string)
 	})
 }
 
+func (s *Service) NotifyKnock(hostUserID, guestUsername, roomID string) error {
+	return s.SendNotification(hostUserID, NotificationPayload{
+		Title: fmt.Sprintf("%s просится на звонок", guestUsername),
+		Body:  "Зайдите в комнату, чтобы впустить или отклонить",
+		Icon:  "/logo192.png",
+		Data: map[string]interface{}{
+			"type":      "lobby_knock",
+			"roomId":    roomID,
+			"guestName": guestUsername,
+		},
+	})
+}
+
 func (s *Service) GetPublicKey() string {
 	return s.vapidPublicKey
 }
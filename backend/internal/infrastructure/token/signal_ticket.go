@@ -0,0 +1,135 @@
+package token
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+	"videocall/internal/domain/repositories"
+	"videocall/internal/infrastructure/auth"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ticketPayload is signed into the ticket, so that any instance sharing the secret can redeem it
+type ticketPayload struct {
+	UserID   string `json:"uid"`
+	Username string `json:"name"`
+	RoomID   string `json:"room"`
+	// TokenExpiry is unix time when jwt the ticket was issued for expires, zero when it does not
+	TokenExpiry int64 `json:"jwt_exp,omitempty"`
+	// Expiry is unix milliseconds after which the ticket is refused
+	Expiry int64  `json:"exp"`
+	Nonce  string `json:"nonce"`
+}
+
+// SignalTicketService issues short-lived tickets exchanged for a signaling connection,
+// so that jwt never appears in websocket URL. Tickets are signed and carry their claims, the instance
+// that redeems a ticket does not have to be the one that issued it. Redeemed tickets are kept in shared storage,
+// so that a ticket is accepted once by the whole cluster
+type SignalTicketService struct {
+	key      []byte
+	ttl      time.Duration
+	redeemed repositories.SignalTicketRepositoryInterface
+}
+
+func NewSignalTicketService(redeemed repositories.SignalTicketRepositoryInterface, secret string, ttl time.Duration) *SignalTicketService {
+	return &SignalTicketService{
+		key:      ticketKey(secret),
+		ttl:      ttl,
+		redeemed: redeemed,
+	}
+}
+
+// ticketKey derives ticket signing key, so that a ticket can never pass for jwt signed with the same secret
+func ticketKey(secret string) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte("videocall-signal-ticket-v1"))
+	return h.Sum(nil)
+}
+
+func (s *SignalTicketService) Issue(claims *auth.Claims) (string, time.Time, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", time.Time{}, err
+	}
+
+	expiry := time.Now().Add(s.ttl)
+	payload := ticketPayload{
+		UserID:   claims.UserID,
+		Username: claims.Username,
+		RoomID:   claims.RoomID,
+		Expiry:   expiry.UnixMilli(),
+		Nonce:    base64.RawURLEncoding.EncodeToString(nonce),
+	}
+	if claims.ExpiresAt != nil {
+		payload.TokenExpiry = claims.ExpiresAt.Unix()
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	body := base64.RawURLEncoding.EncodeToString(data)
+
+	return body + "." + base64.RawURLEncoding.EncodeToString(s.sign(body)), expiry, nil
+}
+
+// Redeem returns claims the ticket was issued for. Ticket can be redeemed only once
+func (s *SignalTicketService) Redeem(ticket string) (*auth.Claims, bool) {
+	body, sig, ok := strings.Cut(ticket, ".")
+	if !ok {
+		return nil, false
+	}
+
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, s.sign(body)) {
+		return nil, false
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(body)
+	if err != nil {
+		return nil, false
+	}
+
+	var payload ticketPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, false
+	}
+
+	expiry := time.UnixMilli(payload.Expiry)
+	if expiry.Before(time.Now()) {
+		return nil, false
+	}
+
+	fresh, err := s.redeemed.MarkRedeemed(payload.Nonce, expiry)
+	if err != nil {
+		log.Printf("failed to redeem signal ticket: %v", err)
+		return nil, false
+	}
+	if !fresh {
+		return nil, false
+	}
+
+	claims := &auth.Claims{
+		UserID:   payload.UserID,
+		Username: payload.Username,
+		RoomID:   payload.RoomID,
+	}
+	if payload.TokenExpiry != 0 {
+		claims.ExpiresAt = jwt.NewNumericDate(time.Unix(payload.TokenExpiry, 0))
+	}
+
+	return claims, true
+}
+
+func (s *SignalTicketService) sign(body string) []byte {
+	h := hmac.New(sha256.New, s.key)
+	h.Write([]byte(body))
+	return h.Sum(nil)
+}
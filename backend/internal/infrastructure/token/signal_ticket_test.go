@@ -0,0 +1,84 @@
+package token
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+	"videocall/internal/domain/repositories/mem"
+	"videocall/internal/infrastructure/auth"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestSignalTicketRedeemedOnAnotherInstance(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// instances share redeemed tickets like they share database
+	redeemed := mem.NewSignalTicketRepository(ctx)
+	issuer := NewSignalTicketService(redeemed, "secret", time.Minute)
+	other := NewSignalTicketService(redeemed, "secret", time.Minute)
+
+	tokenExpiry := time.Now().Add(time.Hour).Truncate(time.Second)
+	ticket, _, err := issuer.Issue(&auth.Claims{
+		UserID:           "user",
+		Username:         "alice",
+		RoomID:           "room",
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(tokenExpiry)},
+	})
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	claims, ok := other.Redeem(ticket)
+	if !ok {
+		t.Fatal("ticket issued by another instance was refused")
+	}
+	if claims.UserID != "user" || claims.Username != "alice" || claims.RoomID != "room" {
+		t.Fatalf("redeemed claims %+v", claims)
+	}
+	if claims.ExpiresAt == nil || !claims.ExpiresAt.Equal(tokenExpiry) {
+		t.Fatalf("redeemed jwt expiry %v, want %v", claims.ExpiresAt, tokenExpiry)
+	}
+
+	if _, ok := other.Redeem(ticket); ok {
+		t.Fatal("ticket was redeemed twice")
+	}
+	if _, ok := issuer.Redeem(ticket); ok {
+		t.Fatal("ticket redeemed on one instance was accepted by another")
+	}
+}
+
+func TestSignalTicketRefused(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	redeemed := mem.NewSignalTicketRepository(ctx)
+	s := NewSignalTicketService(redeemed, "secret", time.Minute)
+	ticket, _, err := s.Issue(&auth.Claims{UserID: "user", RoomID: "room"})
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	body, sig, _ := strings.Cut(ticket, ".")
+
+	expired := NewSignalTicketService(redeemed, "secret", -time.Second)
+	expiredTicket, _, _ := expired.Issue(&auth.Claims{UserID: "user", RoomID: "room"})
+
+	tests := map[string]struct {
+		service *SignalTicketService
+		ticket  string
+	}{
+		"other secret": {NewSignalTicketService(redeemed, "other", time.Minute), ticket},
+		"tampered":     {s, body + "x." + sig},
+		"no signature": {s, body},
+		"garbage":      {s, "not a ticket"},
+		"expired":      {expired, expiredTicket},
+	}
+
+	for name, tt := range tests {
+		if _, ok := tt.service.Redeem(tt.ticket); ok {
+			t.Errorf("%s ticket was accepted", name)
+		}
+	}
+}
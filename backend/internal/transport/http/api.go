@@ -19,6 +19,29 @@ type ServiceHandlerInterface interface {
 	HandleSubscribePush(w http.ResponseWriter, r *http.Request)
 	HandleUnsubscribePush(w http.ResponseWriter, r *http.Request)
 	HandleGetVapidPublicKey(w http.ResponseWriter, r *http.Request)
+	HandleIssueSignalTicket(w http.ResponseWriter, r *http.Request)
+	HandleListMessages(w http.ResponseWriter, r *http.Request)
+	HandleKick(w http.ResponseWriter, r *http.Request)
+	HandleMuteRequest(w http.ResponseWriter, r *http.Request)
+	HandleEndMeeting(w http.ResponseWriter, r *http.Request)
+	HandleLobby(w http.ResponseWriter, r *http.Request)
+	HandleAdmit(w http.ResponseWriter, r *http.Request)
+	HandleDeny(w http.ResponseWriter, r *http.Request)
+	HandleSetPasscode(w http.ResponseWriter, r *http.Request)
+	HandleLockRoom(w http.ResponseWriter, r *http.Request)
+	HandleUnlockRoom(w http.ResponseWriter, r *http.Request)
+	HandleListRooms(w http.ResponseWriter, r *http.Request)
+	HandleGetRoom(w http.ResponseWriter, r *http.Request)
+	HandleUpdateRoom(w http.ResponseWriter, r *http.Request)
+	HandleDeleteRoom(w http.ResponseWriter, r *http.Request)
+	HandleRoomCalendar(w http.ResponseWriter, r *http.Request)
+	HandleListOccurrences(w http.ResponseWriter, r *http.Request)
+	HandleSetSlug(w http.ResponseWriter, r *http.Request)
+	HandleReleaseSlug(w http.ResponseWriter, r *http.Request)
+	HandleTransferRoom(w http.ResponseWriter, r *http.Request)
+	HandleOccupancy(w http.ResponseWriter, r *http.Request)
+	HandlePublicOccupancy(w http.ResponseWriter, r *http.Request)
+	HandleListCalls(w http.ResponseWriter, r *http.Request)
 }
 
 type API struct {
@@ -98,14 +121,93 @@ func (api *API) RegisterHandlers() {
 
 	// Room endpoints
 	http.HandleFunc("/api/rooms", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodPost {
+		switch r.Method {
+		case http.MethodGet:
+			api.processor.HandleListRooms(w, r)
+		case http.MethodPost:
+			api.processor.HandleCreateRoom(w, r)
+		default:
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
-			return
 		}
-		api.processor.HandleCreateRoom(w, r)
 	})
 
 	http.HandleFunc("/api/rooms/", func(w http.ResponseWriter, r *http.Request) {
+		// /api/rooms/{id}
+		if parts := strings.Split(strings.TrimSuffix(r.URL.Path, "/"), "/"); len(parts) == 4 && parts[3] != "" {
+			switch r.Method {
+			case http.MethodGet:
+				api.processor.HandleGetRoom(w, r)
+			case http.MethodPatch:
+				api.processor.HandleUpdateRoom(w, r)
+			case http.MethodDelete:
+				api.processor.HandleDeleteRoom(w, r)
+			default:
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			}
+			return
+		}
+
+		if strings.HasSuffix(r.URL.Path, "/messages") {
+			if r.Method != http.MethodGet {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			api.processor.HandleListMessages(w, r)
+			return
+		}
+
+		if strings.HasSuffix(r.URL.Path, "/meeting.ics") {
+			if r.Method != http.MethodGet {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			api.processor.HandleRoomCalendar(w, r)
+			return
+		}
+
+		if strings.HasSuffix(r.URL.Path, "/occurrences") {
+			if r.Method != http.MethodGet {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			api.processor.HandleListOccurrences(w, r)
+			return
+		}
+
+		if strings.HasSuffix(r.URL.Path, "/occupancy") || strings.HasSuffix(r.URL.Path, "/occupancy/public") {
+			if r.Method != http.MethodGet {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			if strings.HasSuffix(r.URL.Path, "/public") {
+				api.processor.HandlePublicOccupancy(w, r)
+			} else {
+				api.processor.HandleOccupancy(w, r)
+			}
+			return
+		}
+
+		if strings.HasSuffix(r.URL.Path, "/slug") {
+			switch r.Method {
+			case http.MethodPost:
+				api.processor.HandleSetSlug(w, r)
+			case http.MethodDelete:
+				api.processor.HandleReleaseSlug(w, r)
+			default:
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			}
+			return
+		}
+
+		if strings.HasSuffix(r.URL.Path, "/lobby") {
+			if r.Method != http.MethodGet {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			api.processor.HandleLobby(w, r)
+			return
+		}
+
 		if r.Method != http.MethodPost {
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 			return
@@ -126,9 +228,70 @@ func (api *API) RegisterHandlers() {
 			return
 		}
 
+		if strings.HasSuffix(r.URL.Path, "/kick") {
+			api.processor.HandleKick(w, r)
+			return
+		}
+
+		if strings.HasSuffix(r.URL.Path, "/mute") {
+			api.processor.HandleMuteRequest(w, r)
+			return
+		}
+
+		if strings.HasSuffix(r.URL.Path, "/end") {
+			api.processor.HandleEndMeeting(w, r)
+			return
+		}
+
+		if strings.HasSuffix(r.URL.Path, "/admit") {
+			api.processor.HandleAdmit(w, r)
+			return
+		}
+
+		if strings.HasSuffix(r.URL.Path, "/deny") {
+			api.processor.HandleDeny(w, r)
+			return
+		}
+
+		if strings.HasSuffix(r.URL.Path, "/passcode") {
+			api.processor.HandleSetPasscode(w, r)
+			return
+		}
+
+		if strings.HasSuffix(r.URL.Path, "/transfer") {
+			api.processor.HandleTransferRoom(w, r)
+			return
+		}
+
+		if strings.HasSuffix(r.URL.Path, "/unlock") {
+			api.processor.HandleUnlockRoom(w, r)
+			return
+		}
+
+		if strings.HasSuffix(r.URL.Path, "/lock") {
+			api.processor.HandleLockRoom(w, r)
+			return
+		}
+
 		http.Error(w, "method is not supported yet", http.StatusMethodNotAllowed)
 	})
 
+	http.HandleFunc("/api/calls", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		api.processor.HandleListCalls(w, r)
+	})
+
+	http.HandleFunc("/api/signal/ticket", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		api.processor.HandleIssueSignalTicket(w, r)
+	})
+
 	http.HandleFunc("/api/turn", func(w http.ResponseWriter, r *http.Request) {
 		api.processor.HandleTurn(w, r)
 	})
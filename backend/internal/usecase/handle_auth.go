@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 	"videocall/internal/domain/entity"
@@ -227,6 +228,16 @@ func (s *ApiUseCases) HandleRefreshToken(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// room token of a protected room is renewed only for the host and current participants
+	if req.RoomID != "" {
+		room, ok := s.roomRepository.GetRoom(req.RoomID)
+		restricted := ok && room.Restricted()
+		if restricted && room.CreatorUserID != tok.UserID && !slices.Contains(s.connections.RoomUserIDs(req.RoomID), tok.UserID) {
+			http.Error(w, "join the room first", http.StatusForbidden)
+			return
+		}
+	}
+
 	jwtStr, _, err := s.jwt.Issue(tok.UserID, user.Username, req.RoomID)
 	if err != nil {
 		log.Printf("failed to generate jwt: %v", err)
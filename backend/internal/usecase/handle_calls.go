@@ -0,0 +1,138 @@
+package usecase
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+	"videocall/internal/domain/entity"
+	"videocall/internal/domain/repositories"
+)
+
+const (
+	defaultCallPageSize = 20
+	maxCallPageSize     = 100
+)
+
+type CallParticipantResponse struct {
+	UserID   string `json:"user_id"`
+	Username string `json:"username"`
+	JoinedAt int64  `json:"joined_at"`
+	// LeftAt is omitted while the participant is connected
+	LeftAt int64 `json:"left_at,omitempty"`
+}
+
+type CallResponse struct {
+	ID        int64  `json:"id"`
+	RoomID    string `json:"room_id"`
+	StartedAt int64  `json:"started_at"`
+	// EndedAt is omitted while the call is in progress
+	EndedAt         int64                     `json:"ended_at,omitempty"`
+	DurationSeconds int64                     `json:"duration_seconds"`
+	Active          bool                      `json:"active"`
+	Participants    []CallParticipantResponse `json:"participants"`
+}
+
+func callResponse(call *entity.Call) CallResponse {
+	resp := CallResponse{
+		ID:              call.ID,
+		RoomID:          call.RoomID,
+		StartedAt:       call.StartedAt.Unix(),
+		DurationSeconds: int64(call.Duration / time.Second),
+		Active:          call.Active(),
+		Participants:    make([]CallParticipantResponse, 0, len(call.Participants)),
+	}
+
+	if call.Active() {
+		// duration so far
+		resp.DurationSeconds = int64(time.Since(call.StartedAt) / time.Second)
+	} else {
+		resp.EndedAt = call.EndedAt.Unix()
+	}
+
+	for _, p := range call.Participants {
+		participant := CallParticipantResponse{
+			UserID:   p.UserID,
+			Username: p.Username,
+			JoinedAt: p.JoinedAt.Unix(),
+		}
+		if !p.LeftAt.IsZero() {
+			participant.LeftAt = p.LeftAt.Unix()
+		}
+		resp.Participants = append(resp.Participants, participant)
+	}
+
+	return resp
+}
+
+// HandleListCalls returns call history of the caller, newest first.
+// Filters: ?room_id=, ?from= and ?to= (unix or RFC 3339) bound call start, ?before=<id> pages back, next_before is set while older calls may exist
+func (s *ApiUseCases) HandleListCalls(w http.ResponseWriter, r *http.Request) {
+	token, claims, err := s.validateAuthHeader(r)
+	if err != nil || !token.Valid {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := repositories.CallFilter{
+		UserID: claims.UserID,
+		Limit:  defaultCallPageSize,
+	}
+
+	if roomID := query.Get("room_id"); roomID != "" {
+		// slug of a persistent room is accepted too
+		if room, ok := s.resolveRoom(roomID); ok {
+			roomID = room.ID
+		}
+		filter.RoomID = roomID
+	}
+
+	if filter.From, err = parseRangeTime(query.Get("from"), time.Time{}); err != nil {
+		http.Error(w, "invalid from", http.StatusBadRequest)
+		return
+	}
+
+	if filter.To, err = parseRangeTime(query.Get("to"), time.Time{}); err != nil {
+		http.Error(w, "invalid to", http.StatusBadRequest)
+		return
+	}
+
+	if v := query.Get("before"); v != "" {
+		filter.BeforeID, err = strconv.ParseInt(v, 10, 64)
+		if err != nil || filter.BeforeID <= 0 {
+			http.Error(w, "invalid before", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if v := query.Get("limit"); v != "" {
+		filter.Limit, err = strconv.Atoi(v)
+		if err != nil || filter.Limit <= 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = min(filter.Limit, maxCallPageSize)
+	}
+
+	calls, err := s.callRepository.ListCalls(filter)
+	if err != nil {
+		log.Printf("failed to list calls of %s: %v", claims.UserID, err)
+		http.Error(w, "cannot load call history", http.StatusInternalServerError)
+		return
+	}
+
+	resp := make([]CallResponse, 0, len(calls))
+	for _, call := range calls {
+		resp = append(resp, callResponse(call))
+	}
+
+	page := map[string]interface{}{
+		"calls": resp,
+	}
+	if len(calls) == filter.Limit {
+		page["next_before"] = calls[len(calls)-1].ID
+	}
+
+	writeJSON(w, page)
+}
@@ -0,0 +1,102 @@
+package usecase
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultChatPageSize = 50
+	maxChatPageSize     = 200
+)
+
+type ChatMessageResponse struct {
+	ID       int64  `json:"id"`
+	UserID   string `json:"user_id"`
+	Username string `json:"username"`
+	Text     string `json:"text"`
+	SentAt   int64  `json:"sent_at"`
+}
+
+// HandleListMessages returns chat history page of the room. ?before=<id> pages back, next_before is set while older messages may exist
+func (s *ApiUseCases) HandleListMessages(w http.ResponseWriter, r *http.Request) {
+	token, claims, err := s.validateAuthHeader(r)
+	if err != nil || !token.Valid {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) < 4 {
+		http.Error(w, "room not specified", http.StatusBadRequest)
+		return
+	}
+	room, ok := s.resolveRoom(parts[3])
+	if !ok {
+		http.Error(w, fmt.Sprintf("room not found %s", parts[3]), http.StatusNotFound)
+		return
+	}
+	roomID := room.ID
+
+	if claims.RoomID != roomID && room.CreatorUserID != claims.UserID {
+		http.Error(w, "not a member of the room", http.StatusForbidden)
+		return
+	}
+
+	// room token outlives a kick, so it does not prove membership of a banned user
+	if s.roomRepository.IsBanned(roomID, claims.UserID) {
+		http.Error(w, "removed from the room by host", http.StatusForbidden)
+		return
+	}
+
+	query := r.URL.Query()
+
+	var beforeID int64
+	if before := query.Get("before"); before != "" {
+		beforeID, err = strconv.ParseInt(before, 10, 64)
+		if err != nil || beforeID < 0 {
+			http.Error(w, "invalid before", http.StatusBadRequest)
+			return
+		}
+	}
+
+	limit := defaultChatPageSize
+	if l := query.Get("limit"); l != "" {
+		limit, err = strconv.Atoi(l)
+		if err != nil || limit <= 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(limit, maxChatPageSize)
+	}
+
+	msgs, err := s.chatRepository.ListMessages(roomID, beforeID, limit)
+	if err != nil {
+		log.Printf("Failed to list chat messages of room %s: %v", roomID, err)
+		http.Error(w, "failed to list messages", http.StatusInternalServerError)
+		return
+	}
+
+	messages := make([]ChatMessageResponse, 0, len(msgs))
+	for _, msg := range msgs {
+		messages = append(messages, ChatMessageResponse{
+			ID:       msg.ID,
+			UserID:   msg.UserID,
+			Username: msg.Username,
+			Text:     msg.Text,
+			SentAt:   msg.CreatedAt.UnixMilli(),
+		})
+	}
+
+	resp := map[string]interface{}{
+		"messages": messages,
+	}
+	if len(msgs) == limit {
+		resp["next_before"] = msgs[0].ID
+	}
+
+	writeJSON(w, resp)
+}
@@ -0,0 +1,101 @@
+package usecase
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"videocall/internal/domain/repositories"
+	"videocall/internal/infrastructure/auth"
+)
+
+// passLobby parks join request of a lobby room until the host decides. Without request_id a new request is created,
+// with it the call waits up to LobbyWait for the decision. Returns true once admitted, otherwise the response is written
+func (s *ApiUseCases) passLobby(w http.ResponseWriter, r *http.Request, claims *auth.Claims, roomID, hostID string, req JoinRoomRequest) bool {
+	if req.RequestID == "" {
+		knock, delivered := s.connections.Knock(roomID, hostID, claims.UserID, claims.Username)
+		if !delivered && s.pushService != nil {
+			go func() {
+				if err := s.pushService.NotifyKnock(hostID, claims.Username, roomID); err != nil {
+					log.Printf("Failed to send knock notification: %v", err)
+				}
+			}()
+		}
+
+		writeLobbyStatus(w, knock, s.cfg.RoomConfig.LobbyTimeout.Seconds())
+		return false
+	}
+
+	knock, err := s.connections.WaitLobby(r.Context(), req.RequestID, s.cfg.RoomConfig.LobbyWait)
+	if err != nil || knock.RoomID != roomID || knock.UserID != claims.UserID {
+		http.Error(w, "lobby request not found", http.StatusNotFound)
+		return false
+	}
+
+	if knock.Status == repositories.LobbyAdmitted {
+		return true
+	}
+
+	writeLobbyStatus(w, knock, s.cfg.RoomConfig.LobbyTimeout.Seconds())
+	return false
+}
+
+// writeLobbyStatus answers 202 while pending, 403 when denied and 408 when the host did not answer in time
+func writeLobbyStatus(w http.ResponseWriter, knock repositories.LobbyRequest, timeout float64) {
+	status := http.StatusAccepted
+	switch knock.Status {
+	case repositories.LobbyDenied:
+		status = http.StatusForbidden
+	case repositories.LobbyTimedOut:
+		status = http.StatusRequestTimeout
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	writeJSON(w, map[string]interface{}{
+		"status":     knock.Status,
+		"request_id": knock.ID,
+		"timeout":    int(timeout),
+	})
+}
+
+// HandleLobby lists requests waiting for the host
+func (s *ApiUseCases) HandleLobby(w http.ResponseWriter, r *http.Request) {
+	_, roomID, _, ok := s.moderationRequest(w, r)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"requests": s.connections.PendingKnocks(roomID),
+	})
+}
+
+func (s *ApiUseCases) HandleAdmit(w http.ResponseWriter, r *http.Request) {
+	s.resolveLobby(w, r, true)
+}
+
+func (s *ApiUseCases) HandleDeny(w http.ResponseWriter, r *http.Request) {
+	s.resolveLobby(w, r, false)
+}
+
+func (s *ApiUseCases) resolveLobby(w http.ResponseWriter, r *http.Request, admit bool) {
+	_, roomID, req, ok := s.moderationRequest(w, r)
+	if !ok {
+		return
+	}
+
+	knock, err := s.connections.ResolveLobby(roomID, req.RequestID, admit)
+	switch {
+	case errors.Is(err, repositories.ErrLobbyRequestNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, repositories.ErrLobbyRequestResolved):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	writeJSON(w, map[string]string{
+		"status":     knock.Status,
+		"request_id": knock.ID,
+	})
+}
@@ -0,0 +1,95 @@
+package usecase
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"videocall/internal/domain/entity"
+)
+
+type joinResponse struct {
+	RoomID    string `json:"room_id"`
+	JWT       string `json:"jwt"`
+	Status    string `json:"status"`
+	RequestID string `json:"request_id"`
+}
+
+func (e *testEnv) join(t *testing.T, userID, roomID string, req JoinRoomRequest) (int, joinResponse) {
+	t.Helper()
+
+	w := e.request(t, e.api.HandleJoinRoom, userID, "/api/rooms/"+roomID+"/join", req)
+
+	var resp joinResponse
+	// refusals other than lobby decisions are plain text
+	if w.Header().Get("Content-Type") == "application/json" {
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("malformed join response %s: %v", w.Body.String(), err)
+		}
+	}
+
+	return w.Code, resp
+}
+
+func newLobbyEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	e := newTestEnv(t)
+	e.rooms.AddRoom(&entity.Room{ID: "lobby", CreatorUserID: "host", Lobby: true})
+
+	return e
+}
+
+func TestLobbyAdmit(t *testing.T) {
+	e := newLobbyEnv(t)
+
+	status, knock := e.join(t, "guest", "lobby", JoinRoomRequest{})
+	if status != http.StatusAccepted || knock.RequestID == "" {
+		t.Fatalf("knock: status %d, request %q, want %d with request_id", status, knock.RequestID, http.StatusAccepted)
+	}
+
+	// poll before the host decides keeps waiting
+	if status, _ := e.join(t, "guest", "lobby", JoinRoomRequest{RequestID: knock.RequestID}); status != http.StatusAccepted {
+		t.Fatalf("pending poll: status %d, want %d", status, http.StatusAccepted)
+	}
+
+	// request of one user cannot be used by another
+	if status, _ := e.join(t, "mallory", "lobby", JoinRoomRequest{RequestID: knock.RequestID}); status != http.StatusNotFound {
+		t.Fatalf("foreign request: status %d, want %d", status, http.StatusNotFound)
+	}
+
+	if _, err := e.conns.ResolveLobby("lobby", knock.RequestID, true); err != nil {
+		t.Fatalf("admit: %v", err)
+	}
+
+	status, joined := e.join(t, "guest", "lobby", JoinRoomRequest{RequestID: knock.RequestID})
+	if status != http.StatusOK || joined.JWT == "" || joined.RoomID != "lobby" {
+		t.Fatalf("admitted poll: status %d, response %+v", status, joined)
+	}
+}
+
+func TestLobbyDeny(t *testing.T) {
+	e := newLobbyEnv(t)
+
+	_, knock := e.join(t, "guest", "lobby", JoinRoomRequest{})
+	if _, err := e.conns.ResolveLobby("lobby", knock.RequestID, false); err != nil {
+		t.Fatalf("deny: %v", err)
+	}
+
+	status, denied := e.join(t, "guest", "lobby", JoinRoomRequest{RequestID: knock.RequestID})
+	if status != http.StatusForbidden || denied.JWT != "" {
+		t.Fatalf("denied poll: status %d, response %+v", status, denied)
+	}
+
+	if _, err := e.conns.ResolveLobby("lobby", knock.RequestID, true); err == nil {
+		t.Fatal("resolved request admitted again")
+	}
+}
+
+func TestLobbyHostBypasses(t *testing.T) {
+	e := newLobbyEnv(t)
+
+	status, joined := e.join(t, "host", "lobby", JoinRoomRequest{})
+	if status != http.StatusOK || joined.JWT == "" || joined.RoomID != "lobby" {
+		t.Fatalf("host join: status %d, response %+v", status, joined)
+	}
+}
@@ -0,0 +1,120 @@
+package usecase
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"videocall/internal/infrastructure/auth"
+	"videocall/internal/infrastructure/messaging"
+)
+
+type ModerationRequest struct {
+	UserID    string `json:"user_id"`
+	Reason    string `json:"reason,omitempty"`
+	Kind      string `json:"kind,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+	Passcode  string `json:"passcode,omitempty"`
+}
+
+// HandleKick disconnects the user from the room and blocks rejoining
+func (s *ApiUseCases) HandleKick(w http.ResponseWriter, r *http.Request) {
+	claims, roomID, req, ok := s.moderationRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if req.UserID == "" || req.UserID == claims.UserID {
+		http.Error(w, "user_id required", http.StatusBadRequest)
+		return
+	}
+
+	present := s.connections.Kick(roomID, req.UserID, req.Reason)
+
+	log.Printf("🚫 %s kicked user %s from room %s", claims.Username, req.UserID, roomID)
+
+	writeJSON(w, map[string]interface{}{
+		"status":  "kicked",
+		"present": present,
+	})
+}
+
+// HandleMuteRequest asks the user to mute. Clients decide themselves, the server can not mute a peer-to-peer stream
+func (s *ApiUseCases) HandleMuteRequest(w http.ResponseWriter, r *http.Request) {
+	claims, roomID, req, ok := s.moderationRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if req.UserID == "" {
+		http.Error(w, "user_id required", http.StatusBadRequest)
+		return
+	}
+
+	env := messaging.NewEnvelope(messaging.TypeMuteRequest, messaging.ModerationPayload{Reason: req.Reason, Kind: req.Kind})
+	env.From = claims.UserID
+	env.FromName = claims.Username
+	env.To = req.UserID
+
+	if !s.connections.Deliver(roomID, req.UserID, env) {
+		http.Error(w, "user is not in the room", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, map[string]string{
+		"status": "requested",
+	})
+}
+
+// HandleEndMeeting disconnects everyone. The room is deleted unless it is a recurring series or has a slug
+func (s *ApiUseCases) HandleEndMeeting(w http.ResponseWriter, r *http.Request) {
+	claims, roomID, req, ok := s.moderationRequest(w, r)
+	if !ok {
+		return
+	}
+
+	s.connections.EndRoom(roomID, req.Reason)
+
+	log.Printf("✅ %s ended meeting in room %s", claims.Username, roomID)
+
+	writeJSON(w, map[string]string{
+		"status": "ended",
+	})
+}
+
+// moderationRequest authorizes the room host and decodes optional request body
+func (s *ApiUseCases) moderationRequest(w http.ResponseWriter, r *http.Request) (*auth.Claims, string, ModerationRequest, bool) {
+	var req ModerationRequest
+
+	token, claims, err := s.validateAuthHeader(r)
+	if err != nil || !token.Valid {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return nil, "", req, false
+	}
+
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) < 4 {
+		http.Error(w, "room not specified", http.StatusBadRequest)
+		return nil, "", req, false
+	}
+	room, ok := s.resolveRoom(parts[3])
+	if !ok {
+		http.Error(w, fmt.Sprintf("room not found %s", parts[3]), http.StatusNotFound)
+		return nil, "", req, false
+	}
+
+	if room.CreatorUserID != claims.UserID {
+		http.Error(w, "only the room host can do this", http.StatusForbidden)
+		return nil, "", req, false
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return nil, "", req, false
+	}
+
+	return claims, room.ID, req, true
+}
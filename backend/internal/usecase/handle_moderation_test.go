@@ -0,0 +1,26 @@
+package usecase
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAdmissionBanned(t *testing.T) {
+	e := newTestEnv(t)
+
+	e.conns.Kick(testRoom, "mallory", "")
+
+	if _, status := e.dial(t, "mallory", testRoom, ""); status != http.StatusForbidden {
+		t.Fatalf("banned user: status %d, want %d", status, http.StatusForbidden)
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/api/rooms/"+testRoom+"/messages", nil)
+	// room token issued before the kick
+	r.Header.Set("Authorization", "Bearer "+e.token(t, "mallory", testRoom))
+	e.api.HandleListMessages(w, r)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("chat history for banned user: status %d, want %d", w.Code, http.StatusForbidden)
+	}
+}
@@ -0,0 +1,94 @@
+package usecase
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"videocall/internal/domain/entity"
+	"videocall/internal/domain/repositories"
+)
+
+func (s *ApiUseCases) roomCapacity(room *entity.Room) int {
+	if room.Capacity == 0 {
+		return s.cfg.RoomConfig.DefaultCapacity
+	}
+
+	return room.Capacity
+}
+
+// HandleOccupancy reports who is connected to the room right now. Restricted rooms show it only to the host, invitees and members
+func (s *ApiUseCases) HandleOccupancy(w http.ResponseWriter, r *http.Request) {
+	token, claims, err := s.validateAuthHeader(r)
+	if err != nil || !token.Valid {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) < 4 {
+		http.Error(w, "room not specified", http.StatusBadRequest)
+		return
+	}
+
+	room, ok := s.resolveRoom(parts[3])
+	if !ok {
+		http.Error(w, fmt.Sprintf("room not found %s", parts[3]), http.StatusNotFound)
+		return
+	}
+
+	occupants := s.connections.Occupancy(room.ID)
+
+	present := slices.ContainsFunc(occupants, func(o repositories.Occupant) bool { return o.UserID == claims.UserID })
+	if room.Restricted() && !present && !s.knowsRoom(room, claims.UserID) {
+		http.Error(w, "not a member of the room", http.StatusForbidden)
+		return
+	}
+
+	devices := 0
+	users := make([]map[string]interface{}, 0, len(occupants))
+	for _, o := range occupants {
+		devices += o.Devices
+		users = append(users, map[string]interface{}{
+			"user_id":   o.UserID,
+			"username":  o.Username,
+			"joined_at": o.JoinedAt.Unix(),
+			"devices":   o.Devices,
+			"host":      o.UserID == room.CreatorUserID,
+		})
+	}
+
+	capacity := s.roomCapacity(room)
+
+	writeJSON(w, map[string]interface{}{
+		"room_id":      room.ID,
+		"participants": devices,
+		"capacity":     capacity,
+		"full":         devices >= capacity,
+		"users":        users,
+	})
+}
+
+// HandlePublicOccupancy tells anyone how many devices are in the room and whether it is full
+func (s *ApiUseCases) HandlePublicOccupancy(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) < 4 {
+		http.Error(w, "room not specified", http.StatusBadRequest)
+		return
+	}
+
+	room, ok := s.resolveRoom(parts[3])
+	if !ok {
+		http.Error(w, fmt.Sprintf("room not found %s", parts[3]), http.StatusNotFound)
+		return
+	}
+
+	participants := s.connections.RoomSize(room.ID)
+	capacity := s.roomCapacity(room)
+
+	writeJSON(w, map[string]interface{}{
+		"participants": participants,
+		"capacity":     capacity,
+		"full":         participants >= capacity,
+	})
+}
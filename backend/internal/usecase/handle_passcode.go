@@ -0,0 +1,182 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+	"videocall/internal/domain/entity"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Passcode length bounds, in characters
+const (
+	MinPasscodeLength = 4
+	MaxPasscodeLength = 64
+)
+
+const passcodeCleanInterval = 60 * time.Second
+
+// passcodeThrottle counts wrong passcodes per room. Once the limit is reached within the window
+// the room refuses passcode checks until the window passes, whoever is trying
+type passcodeThrottle struct {
+	maxAttempts int
+	window      time.Duration
+	mu          sync.Mutex
+	rooms       map[string]*passcodeAttempts
+}
+
+type passcodeAttempts struct {
+	failures int
+	since    time.Time
+}
+
+func newPasscodeThrottle(ctx context.Context, maxAttempts int, window time.Duration) *passcodeThrottle {
+	t := &passcodeThrottle{
+		maxAttempts: maxAttempts,
+		window:      window,
+		rooms:       make(map[string]*passcodeAttempts),
+	}
+
+	go func() {
+		ticker := time.NewTicker(passcodeCleanInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				t.mu.Lock()
+				for roomID, a := range t.rooms {
+					if time.Since(a.since) > t.window {
+						delete(t.rooms, roomID)
+					}
+				}
+				t.mu.Unlock()
+			}
+		}
+	}()
+
+	return t
+}
+
+// blocked returns how long passcode checks of the room are refused
+func (t *passcodeThrottle) blocked(roomID string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	a, ok := t.rooms[roomID]
+	if !ok || a.failures < t.maxAttempts {
+		return 0
+	}
+
+	return max(t.window-time.Since(a.since), 0)
+}
+
+func (t *passcodeThrottle) fail(roomID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	a, ok := t.rooms[roomID]
+	if !ok || time.Since(a.since) > t.window {
+		a = &passcodeAttempts{since: time.Now()}
+		t.rooms[roomID] = a
+	}
+	a.failures++
+}
+
+// checkPasscode verifies join passcode of the room. On failure the response is written
+func (s *ApiUseCases) checkPasscode(w http.ResponseWriter, room *entity.Room, roomID, passcode string) bool {
+	if room.PasscodeHash == "" {
+		return true
+	}
+
+	if retry := s.passcodes.blocked(roomID); retry > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(retry.Seconds())+1))
+		http.Error(w, "too many wrong passcodes, try later", http.StatusTooManyRequests)
+		return false
+	}
+
+	if passcode == "" {
+		http.Error(w, "passcode required", http.StatusForbidden)
+		return false
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(room.PasscodeHash), []byte(passcode)); err != nil {
+		s.passcodes.fail(roomID)
+		log.Printf("Wrong passcode for room %s", roomID)
+		http.Error(w, "wrong passcode", http.StatusForbidden)
+		return false
+	}
+
+	return true
+}
+
+// hashPasscode validates passcode and returns its hash, empty passcode gives empty hash
+func hashPasscode(passcode string) (string, error) {
+	if passcode == "" {
+		return "", nil
+	}
+
+	if n := utf8.RuneCountInString(passcode); n < MinPasscodeLength || n > MaxPasscodeLength {
+		return "", fmt.Errorf("passcode must be between %d and %d characters", MinPasscodeLength, MaxPasscodeLength)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(passcode), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+// HandleSetPasscode sets or, with empty passcode, removes join passcode of the room
+func (s *ApiUseCases) HandleSetPasscode(w http.ResponseWriter, r *http.Request) {
+	claims, roomID, req, ok := s.moderationRequest(w, r)
+	if !ok {
+		return
+	}
+
+	hash, err := hashPasscode(req.Passcode)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.roomRepository.SetPasscode(roomID, hash)
+
+	log.Printf("✅ %s updated passcode of room %s", claims.Username, roomID)
+
+	writeJSON(w, map[string]interface{}{
+		"passcode": hash != "",
+	})
+}
+
+func (s *ApiUseCases) HandleLockRoom(w http.ResponseWriter, r *http.Request) {
+	s.setLocked(w, r, true)
+}
+
+func (s *ApiUseCases) HandleUnlockRoom(w http.ResponseWriter, r *http.Request) {
+	s.setLocked(w, r, false)
+}
+
+func (s *ApiUseCases) setLocked(w http.ResponseWriter, r *http.Request, locked bool) {
+	claims, roomID, _, ok := s.moderationRequest(w, r)
+	if !ok {
+		return
+	}
+
+	s.roomRepository.SetLocked(roomID, locked)
+
+	log.Printf("✅ %s set room %s locked=%t", claims.Username, roomID, locked)
+
+	writeJSON(w, map[string]interface{}{
+		"locked": locked,
+	})
+}
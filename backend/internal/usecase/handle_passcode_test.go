@@ -0,0 +1,160 @@
+package usecase
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+	"videocall/internal/domain/entity"
+)
+
+func TestPasscodeThrottle(t *testing.T) {
+	throttle := newPasscodeThrottle(context.Background(), 2, 50*time.Millisecond)
+
+	throttle.fail("a")
+	if retry := throttle.blocked("a"); retry != 0 {
+		t.Fatalf("blocked after one failure for %v", retry)
+	}
+
+	throttle.fail("a")
+	if retry := throttle.blocked("a"); retry <= 0 {
+		t.Fatal("not blocked after max failures")
+	}
+	if retry := throttle.blocked("b"); retry != 0 {
+		t.Fatalf("other room blocked for %v", retry)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if retry := throttle.blocked("a"); retry != 0 {
+		t.Fatalf("still blocked after the window for %v", retry)
+	}
+
+	// failures of the expired window are not counted again
+	throttle.fail("a")
+	if retry := throttle.blocked("a"); retry != 0 {
+		t.Fatalf("blocked after one failure of a new window for %v", retry)
+	}
+}
+
+func newPasscodeEnv(t *testing.T, lobby bool) *testEnv {
+	t.Helper()
+
+	e := newTestEnv(t)
+	hash, err := hashPasscode("1234")
+	if err != nil {
+		t.Fatalf("hash passcode: %v", err)
+	}
+	e.rooms.AddRoom(&entity.Room{ID: "secret", CreatorUserID: "host", PasscodeHash: hash, Lobby: lobby})
+
+	return e
+}
+
+func TestJoinPasscode(t *testing.T) {
+	e := newPasscodeEnv(t, false)
+
+	tests := []struct {
+		name     string
+		userID   string
+		passcode string
+		want     int
+	}{
+		{"missing", "guest", "", http.StatusForbidden},
+		{"wrong", "guest", "0000", http.StatusForbidden},
+		{"correct", "guest", "1234", http.StatusOK},
+		{"host without passcode", "host", "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status, _ := e.join(t, tt.userID, "secret", JoinRoomRequest{Passcode: tt.passcode}); status != tt.want {
+				t.Fatalf("status %d, want %d", status, tt.want)
+			}
+		})
+	}
+}
+
+func TestJoinPasscodeThrottled(t *testing.T) {
+	e := newPasscodeEnv(t, false)
+
+	for i := 0; i < e.api.cfg.RoomConfig.PasscodeMaxAttempts; i++ {
+		if status, _ := e.join(t, "guest", "secret", JoinRoomRequest{Passcode: "0000"}); status != http.StatusForbidden {
+			t.Fatalf("attempt %d: status %d, want %d", i+1, status, http.StatusForbidden)
+		}
+	}
+
+	// correct passcode is refused as well until the window passes
+	w := e.request(t, e.api.HandleJoinRoom, "other", "/api/rooms/secret/join", JoinRoomRequest{Passcode: "1234"})
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("status %d, want %d", w.Code, http.StatusTooManyRequests)
+	}
+	if retry, err := strconv.Atoi(w.Header().Get("Retry-After")); err != nil || retry <= 0 {
+		t.Fatalf("Retry-After %q", w.Header().Get("Retry-After"))
+	}
+}
+
+func TestLobbyRequiresPasscode(t *testing.T) {
+	e := newPasscodeEnv(t, true)
+
+	// made up request_id does not skip the passcode
+	if status, _ := e.join(t, "guest", "secret", JoinRoomRequest{RequestID: "forged"}); status == http.StatusOK {
+		t.Fatal("forged lobby request admitted")
+	}
+
+	if status, _ := e.join(t, "guest", "secret", JoinRoomRequest{}); status != http.StatusForbidden {
+		t.Fatalf("knock without passcode: status %d, want %d", status, http.StatusForbidden)
+	}
+
+	status, knock := e.join(t, "guest", "secret", JoinRoomRequest{Passcode: "1234"})
+	if status != http.StatusAccepted {
+		t.Fatalf("knock with passcode: status %d, want %d", status, http.StatusAccepted)
+	}
+	if _, err := e.conns.ResolveLobby("secret", knock.RequestID, true); err != nil {
+		t.Fatalf("admit: %v", err)
+	}
+
+	// admitted request passed the passcode when it was made
+	if status, _ := e.join(t, "guest", "secret", JoinRoomRequest{RequestID: knock.RequestID}); status != http.StatusOK {
+		t.Fatalf("admitted poll: status %d, want %d", status, http.StatusOK)
+	}
+}
+
+func TestJoinLocked(t *testing.T) {
+	e := newTestEnv(t)
+	e.rooms.SetLocked(testRoom, true)
+
+	if status, _ := e.join(t, "guest", testRoom, JoinRoomRequest{}); status != http.StatusLocked {
+		t.Fatalf("guest: status %d, want %d", status, http.StatusLocked)
+	}
+	if status, _ := e.join(t, "host", testRoom, JoinRoomRequest{}); status != http.StatusOK {
+		t.Fatalf("host: status %d, want %d", status, http.StatusOK)
+	}
+}
+
+func TestAdmissionLocked(t *testing.T) {
+	e := newTestEnv(t)
+	_, session := e.connect(t, "alice")
+	e.waitSize(t, 1)
+
+	e.rooms.SetLocked(testRoom, true)
+
+	tests := []struct {
+		name    string
+		userID  string
+		session string
+		want    int
+	}{
+		{"new participant", "bob", "", http.StatusLocked},
+		{"forged session", "bob", session, http.StatusLocked},
+		{"host", "host", "", http.StatusSwitchingProtocols},
+		{"another device of a participant", "alice", "", http.StatusSwitchingProtocols},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, status := e.dial(t, tt.userID, testRoom, tt.session); status != tt.want {
+				t.Fatalf("status %d, want %d", status, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,285 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"videocall/internal/domain/entity"
+)
+
+const (
+	maxRecurrenceInterval = 99
+	maxRecurrenceCount    = 1000
+	// maxSeriesYears bounds Until of a series
+	maxSeriesYears = 10
+	maxExceptions  = 200
+
+	dateFormat = "2006-01-02"
+
+	defaultOccurrencesRange = 90 * 24 * time.Hour
+	defaultOccurrencesLimit = 50
+	maxOccurrencesLimit     = 500
+)
+
+// maxTime is the open end of a range of occurrences
+var maxTime = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// RecurrenceRequest repeats meeting like RRULE: freq is daily, weekly or monthly, by_day takes MO..SU for weekly series.
+// Series ends after count meetings or at until (date or RFC 3339), runs forever without both.
+// Exceptions are dates of cancelled meetings in the meeting time zone
+type RecurrenceRequest struct {
+	Freq       string   `json:"freq"`
+	Interval   int      `json:"interval,omitempty"`
+	ByDay      []string `json:"by_day,omitempty"`
+	Count      int      `json:"count,omitempty"`
+	Until      string   `json:"until,omitempty"`
+	Exceptions []string `json:"exceptions,omitempty"`
+}
+
+func parseRecurrence(req *RecurrenceRequest, schedule *entity.Schedule) (*entity.Recurrence, error) {
+	loc := schedule.Location()
+	first := schedule.StartsAt.In(loc)
+
+	rec := &entity.Recurrence{
+		Freq:     strings.ToLower(req.Freq),
+		Interval: req.Interval,
+		Count:    req.Count,
+	}
+
+	switch rec.Freq {
+	case entity.FreqDaily, entity.FreqWeekly, entity.FreqMonthly:
+	default:
+		return nil, errors.New("freq must be daily, weekly or monthly")
+	}
+
+	if rec.Interval == 0 {
+		rec.Interval = 1
+	}
+	if rec.Interval < 1 || rec.Interval > maxRecurrenceInterval {
+		return nil, fmt.Errorf("interval must be between 1 and %d", maxRecurrenceInterval)
+	}
+
+	if len(req.ByDay) > 0 {
+		if rec.Freq != entity.FreqWeekly {
+			return nil, errors.New("by_day is supported for weekly series only")
+		}
+		for _, code := range req.ByDay {
+			day, ok := weekdayCodes[strings.ToUpper(code)]
+			if !ok {
+				return nil, fmt.Errorf("unknown weekday %q", code)
+			}
+			if !slices.Contains(rec.ByDay, day) {
+				rec.ByDay = append(rec.ByDay, day)
+			}
+		}
+		// the first meeting must belong to the series
+		if !slices.Contains(rec.ByDay, first.Weekday()) {
+			return nil, errors.New("by_day must include weekday of the start")
+		}
+	}
+
+	if rec.Count < 0 || rec.Count > maxRecurrenceCount {
+		return nil, fmt.Errorf("count must be at most %d", maxRecurrenceCount)
+	}
+
+	if req.Until != "" {
+		if rec.Count > 0 {
+			return nil, errors.New("count and until are mutually exclusive")
+		}
+		until, err := time.Parse(time.RFC3339, req.Until)
+		if err != nil {
+			day, err := time.ParseInLocation(dateFormat, req.Until, loc)
+			if err != nil {
+				return nil, errors.New("until must be RFC 3339 time or " + dateFormat)
+			}
+			// meetings of the whole day are included
+			until = day.AddDate(0, 0, 1).Add(-time.Second)
+		}
+		if until.Before(schedule.StartsAt) {
+			return nil, errors.New("until must be after start")
+		}
+		if until.After(schedule.StartsAt.AddDate(maxSeriesYears, 0, 0)) {
+			return nil, fmt.Errorf("series may last at most %d years", maxSeriesYears)
+		}
+		rec.Until = until.UTC()
+	}
+
+	if len(req.Exceptions) > maxExceptions {
+		return nil, fmt.Errorf("at most %d exceptions allowed", maxExceptions)
+	}
+
+	series := *schedule
+	series.Recurrence = rec
+	for _, date := range req.Exceptions {
+		day, err := time.ParseInLocation(dateFormat, date, loc)
+		if err != nil {
+			return nil, fmt.Errorf("exception %q must be %s", date, dateFormat)
+		}
+
+		var start time.Time
+		for _, occ := range series.Occurrences(day, day.AddDate(0, 0, 1), 2) {
+			if occ.In(loc).Format(dateFormat) == date {
+				start = occ
+			}
+		}
+		if start.IsZero() {
+			return nil, fmt.Errorf("no meeting on %s", date)
+		}
+		if !slices.ContainsFunc(rec.Exceptions, start.Equal) {
+			rec.Exceptions = append(rec.Exceptions, start.UTC())
+		}
+	}
+
+	return rec, nil
+}
+
+func recurrenceInfo(schedule *entity.Schedule) map[string]interface{} {
+	rec := schedule.Recurrence
+	if rec == nil {
+		return nil
+	}
+
+	loc := schedule.Location()
+
+	byDay := make([]string, 0, len(rec.ByDay))
+	for _, day := range rec.ByDay {
+		byDay = append(byDay, strings.ToUpper(day.String()[:2]))
+	}
+
+	exceptions := make([]string, 0, len(rec.Exceptions))
+	for _, start := range rec.Exceptions {
+		exceptions = append(exceptions, start.In(loc).Format(dateFormat))
+	}
+
+	var until interface{}
+	if !rec.Until.IsZero() {
+		until = rec.Until.Unix()
+	}
+
+	return map[string]interface{}{
+		"freq":       rec.Freq,
+		"interval":   rec.Interval,
+		"by_day":     byDay,
+		"count":      rec.Count,
+		"until":      until,
+		"exceptions": exceptions,
+	}
+}
+
+func parseRangeTime(value string, fallback time.Time) (time.Time, error) {
+	if value == "" {
+		return fallback, nil
+	}
+
+	if unix, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(unix, 0), nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
+
+// HandleListOccurrences returns meetings of the room within ?from= and ?to= (unix or RFC 3339), upcoming ones by default.
+// next_after is set when more meetings follow in the range, it is passed as ?after= with the same range to get the next page
+func (s *ApiUseCases) HandleListOccurrences(w http.ResponseWriter, r *http.Request) {
+	token, claims, err := s.validateAuthHeader(r)
+	if err != nil || !token.Valid {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) < 4 {
+		http.Error(w, "room not specified", http.StatusBadRequest)
+		return
+	}
+	room, ok := s.resolveRoom(parts[3])
+	if !ok {
+		http.Error(w, fmt.Sprintf("room not found %s", parts[3]), http.StatusNotFound)
+		return
+	}
+	roomID := room.ID
+
+	if !s.knowsRoom(room, claims.UserID) {
+		http.Error(w, "not a member of the room", http.StatusForbidden)
+		return
+	}
+
+	if room.Schedule == nil {
+		http.Error(w, "room has no scheduled meeting", http.StatusNotFound)
+		return
+	}
+
+	query := r.URL.Query()
+	from, err := parseRangeTime(query.Get("from"), time.Now())
+	if err != nil {
+		http.Error(w, "invalid from", http.StatusBadRequest)
+		return
+	}
+	to, err := parseRangeTime(query.Get("to"), from.Add(defaultOccurrencesRange))
+	if err != nil || !to.After(from) {
+		http.Error(w, "invalid to", http.StatusBadRequest)
+		return
+	}
+
+	var after time.Time
+	if v := query.Get("after"); v != "" {
+		unix, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid after", http.StatusBadRequest)
+			return
+		}
+		// cursor has second precision, meetings of a series are at least a day apart
+		after = time.Unix(unix, 0).Add(time.Second - 1)
+	}
+
+	limit := defaultOccurrencesLimit
+	if v := query.Get("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(limit, maxOccurrencesLimit)
+	}
+
+	loc := room.Schedule.Location()
+	// one more tells whether the page is the last one
+	starts := room.Schedule.OccurrencesAfter(from, to, after, limit+1)
+
+	var nextAfter interface{}
+	if len(starts) > limit {
+		starts = starts[:limit]
+		nextAfter = starts[limit-1].Unix()
+	}
+
+	occurrences := make([]map[string]interface{}, 0, len(starts))
+	for _, start := range starts {
+		occurrences = append(occurrences, map[string]interface{}{
+			"starts_at":   start.Unix(),
+			"ends_at":     start.Add(room.Schedule.Duration).Unix(),
+			"local_start": start.In(loc).Format(localTimeFormat),
+		})
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"room_id":     roomID,
+		"time_zone":   room.Schedule.TimeZone,
+		"join_url":    s.joinURL(r, room),
+		"occurrences": occurrences,
+		"next_after":  nextAfter,
+	})
+}
@@ -0,0 +1,241 @@
+package usecase
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"unicode/utf8"
+	"videocall/internal/domain/entity"
+)
+
+// MaxRoomTitleLength is the longest room title in characters
+const MaxRoomTitleLength = 100
+
+// UpdateRoomRequest changes only the fields that are present
+type UpdateRoomRequest struct {
+	Title    *string `json:"title,omitempty"`
+	Capacity *int    `json:"capacity,omitempty"`
+	Lobby    *bool   `json:"lobby,omitempty"`
+	Locked   *bool   `json:"locked,omitempty"`
+	// Schedule replaces the meeting plan of the room
+	Schedule *ScheduleRequest `json:"schedule,omitempty"`
+}
+
+func roomTitle(title string) (string, error) {
+	title = strings.TrimSpace(title)
+	if utf8.RuneCountInString(title) > MaxRoomTitleLength {
+		return "", fmt.Errorf("title must be at most %d characters", MaxRoomTitleLength)
+	}
+
+	return title, nil
+}
+
+// roomInfo is room metadata shown to its creator and participants
+func (s *ApiUseCases) roomInfo(room *entity.Room) map[string]interface{} {
+	creatorName := ""
+	if creator, err := s.userRepository.GetUser(room.CreatorUserID); err == nil {
+		creatorName = creator.Username
+	}
+
+	userIDs := s.connections.RoomUserIDs(room.ID)
+	if userIDs == nil {
+		userIDs = []string{}
+	}
+
+	var schedule map[string]interface{}
+	if room.Schedule != nil {
+		schedule = s.scheduleInfo(room.Schedule)
+	}
+
+	return map[string]interface{}{
+		"room_id":          room.ID,
+		"title":            room.Title,
+		"slug":             room.Slug,
+		"creator_user_id":  room.CreatorUserID,
+		"creator_username": creatorName,
+		"created_at":       room.CreatedAt.Unix(),
+		"updated_at":       room.UpdatedAt.Unix(),
+		"capacity":         s.roomCapacity(room),
+		"lobby":            room.Lobby,
+		"locked":           room.Locked,
+		"passcode":         room.PasscodeHash != "",
+		"participants":     s.connections.RoomSize(room.ID),
+		"user_ids":         userIDs,
+		"schedule":         schedule,
+	}
+}
+
+// HandleListRooms returns rooms the caller created, joined or was invited to
+func (s *ApiUseCases) HandleListRooms(w http.ResponseWriter, r *http.Request) {
+	token, claims, err := s.validateAuthHeader(r)
+	if err != nil || !token.Valid {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	rooms := make([]map[string]interface{}, 0)
+	for _, room := range s.roomRepository.ListRooms(claims.UserID) {
+		rooms = append(rooms, s.roomInfo(room))
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"rooms": rooms,
+	})
+}
+
+// HandleGetRoom returns metadata of the room. Details of restricted rooms are shown only to the host and users who joined it
+func (s *ApiUseCases) HandleGetRoom(w http.ResponseWriter, r *http.Request) {
+	token, claims, err := s.validateAuthHeader(r)
+	if err != nil || !token.Valid {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) < 4 {
+		http.Error(w, "room not specified", http.StatusBadRequest)
+		return
+	}
+	room, ok := s.resolveRoom(parts[3])
+	if !ok {
+		http.Error(w, fmt.Sprintf("room not found %s", parts[3]), http.StatusNotFound)
+		return
+	}
+
+	if room.Restricted() && !s.knowsRoom(room, claims.UserID) {
+		http.Error(w, "not a member of the room", http.StatusForbidden)
+		return
+	}
+
+	writeJSON(w, s.roomInfo(room))
+}
+
+// knowsRoom reports whether the user hosts, was invited to or joined the room
+func (s *ApiUseCases) knowsRoom(room *entity.Room, userID string) bool {
+	if room.CreatorUserID == userID {
+		return true
+	}
+
+	if room.Schedule != nil && slices.Contains(room.Schedule.Invitees, userID) {
+		return true
+	}
+
+	return s.roomRepository.IsMember(room.ID, userID)
+}
+
+// HandleUpdateRoom changes title and settings of the room, host only
+func (s *ApiUseCases) HandleUpdateRoom(w http.ResponseWriter, r *http.Request) {
+	token, claims, err := s.validateAuthHeader(r)
+	if err != nil || !token.Valid {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) < 4 {
+		http.Error(w, "room not specified", http.StatusBadRequest)
+		return
+	}
+	room, ok := s.resolveRoom(parts[3])
+	if !ok {
+		http.Error(w, fmt.Sprintf("room not found %s", parts[3]), http.StatusNotFound)
+		return
+	}
+	roomID := room.ID
+
+	if room.CreatorUserID != claims.UserID {
+		http.Error(w, "only the room host can do this", http.StatusForbidden)
+		return
+	}
+
+	var req UpdateRoomRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if req.Title != nil {
+		title, err := roomTitle(*req.Title)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		room.Title = title
+	}
+
+	if req.Capacity != nil {
+		if *req.Capacity < MinRoomUsers || *req.Capacity > MaxRoomUsers {
+			http.Error(w, fmt.Sprintf("capacity must be between %d and %d", MinRoomUsers, MaxRoomUsers), http.StatusBadRequest)
+			return
+		}
+		room.Capacity = *req.Capacity
+	}
+
+	var previousInvitees []string
+	if req.Schedule != nil {
+		if room.Schedule != nil {
+			previousInvitees = room.Schedule.Invitees
+		}
+		schedule, err := s.parseSchedule(req.Schedule, claims.UserID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		room.Schedule = schedule
+	}
+
+	if req.Lobby != nil {
+		room.Lobby = *req.Lobby
+	}
+
+	if req.Locked != nil {
+		room.Locked = *req.Locked
+	}
+
+	s.roomRepository.UpdateRoom(room)
+	if req.Schedule != nil {
+		s.notifyInvitees(claims.UserID, claims.Username, roomID, room.Schedule.Invitees, previousInvitees)
+	}
+
+	log.Printf("✅ %s updated settings of room %s", claims.Username, roomID)
+
+	writeJSON(w, s.roomInfo(room))
+}
+
+// HandleDeleteRoom disconnects everybody and deletes the room with its history, host only.
+// Unlike ending a meeting it also removes a recurring series and releases slug of the room
+func (s *ApiUseCases) HandleDeleteRoom(w http.ResponseWriter, r *http.Request) {
+	token, claims, err := s.validateAuthHeader(r)
+	if err != nil || !token.Valid {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) < 4 {
+		http.Error(w, "room not specified", http.StatusBadRequest)
+		return
+	}
+	room, ok := s.resolveRoom(parts[3])
+	if !ok {
+		http.Error(w, fmt.Sprintf("room not found %s", parts[3]), http.StatusNotFound)
+		return
+	}
+	roomID := room.ID
+
+	if room.CreatorUserID != claims.UserID {
+		http.Error(w, "only the room host can do this", http.StatusForbidden)
+		return
+	}
+
+	s.connections.DeleteRoom(roomID, "room deleted")
+
+	log.Printf("🗑️ %s deleted room %s", claims.Username, roomID)
+
+	writeJSON(w, map[string]string{
+		"status": "deleted",
+	})
+}
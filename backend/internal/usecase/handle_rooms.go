@@ -2,7 +2,9 @@ package usecase
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"strings"
@@ -11,8 +13,28 @@ import (
 	"github.com/google/uuid"
 )
 
-// MaxRoomUsers Only 2 users in a room tested so far
-const MaxRoomUsers = 2
+// Room capacity bounds for a full mesh call
+const (
+	MinRoomUsers = 2
+	MaxRoomUsers = 8
+)
+
+type CreateRoomRequest struct {
+	Title    string `json:"title,omitempty"`
+	Capacity int    `json:"capacity,omitempty"`
+	Lobby    bool   `json:"lobby,omitempty"`
+	Passcode string `json:"passcode,omitempty"`
+	// Schedule makes the room a planned meeting
+	Schedule *ScheduleRequest `json:"schedule,omitempty"`
+	// Slug makes the room persistent and reachable by /join/<slug>
+	Slug string `json:"slug,omitempty"`
+}
+
+type JoinRoomRequest struct {
+	Passcode string `json:"passcode,omitempty"`
+	// RequestID of a pending lobby request the caller waits for, passcode is not checked again
+	RequestID string `json:"request_id,omitempty"`
+}
 
 type InviteRequest struct {
 	InvitedUsername string `json:"invited_username"`
@@ -25,8 +47,77 @@ func (s *ApiUseCases) HandleCreateRoom(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var req CreateRoomRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	capacity := req.Capacity
+	if capacity == 0 {
+		capacity = s.cfg.RoomConfig.DefaultCapacity
+	}
+	if capacity < MinRoomUsers || capacity > MaxRoomUsers {
+		http.Error(w, fmt.Sprintf("capacity must be between %d and %d", MinRoomUsers, MaxRoomUsers), http.StatusBadRequest)
+		return
+	}
+
+	title, err := roomTitle(req.Title)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	passcodeHash, err := hashPasscode(req.Passcode)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	slug := ""
+	if req.Slug != "" {
+		if slug, err = normalizeSlug(req.Slug); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !s.canClaimSlug(w, claims.UserID, "") {
+			return
+		}
+		if _, taken := s.roomRepository.GetRoomBySlug(slug); taken {
+			http.Error(w, "slug is taken", http.StatusConflict)
+			return
+		}
+	}
+
+	var schedule *entity.Schedule
+	if req.Schedule != nil {
+		if schedule, err = s.parseSchedule(req.Schedule, claims.UserID); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
 	roomID := strings.Replace(uuid.NewString(), "-", "", -1)
-	s.roomRepository.AddRoom(roomID, claims.UserID)
+	s.roomRepository.AddRoom(&entity.Room{
+		ID:            roomID,
+		Title:         title,
+		CreatorUserID: claims.UserID,
+		Capacity:      capacity,
+		Lobby:         req.Lobby,
+		PasscodeHash:  passcodeHash,
+		Schedule:      schedule,
+	})
+	if slug != "" {
+		if err := s.roomRepository.SetSlug(roomID, slug); err != nil {
+			// claimed by somebody else in the meantime
+			s.roomRepository.DeleteRoom(roomID)
+			http.Error(w, "slug is taken", http.StatusConflict)
+			return
+		}
+	}
+	if schedule != nil {
+		s.notifyInvitees(claims.UserID, claims.Username, roomID, schedule.Invitees, nil)
+	}
 
 	//refresh token to add roomID
 	jwtStr, _, err := s.jwt.Issue(claims.UserID, claims.Username, roomID)
@@ -36,12 +127,28 @@ func (s *ApiUseCases) HandleCreateRoom(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	log.Printf("User %s (%s) created room %s", claims.Username, claims.UserID, roomID)
+	log.Printf("User %s (%s) created room %s (capacity %d)", claims.Username, claims.UserID, roomID, capacity)
 
-	writeJSON(w, map[string]string{
+	var scheduleInfo map[string]interface{}
+	if schedule != nil {
+		scheduleInfo = s.scheduleInfo(schedule)
+	}
+
+	joinURL := "/join/" + roomID
+	if slug != "" {
+		joinURL = "/join/" + slug
+	}
+
+	writeJSON(w, map[string]interface{}{
 		"room_id":  roomID,
+		"title":    title,
+		"slug":     slug,
 		"jwt":      jwtStr,
-		"join_url": "/join/" + roomID,
+		"join_url": joinURL,
+		"capacity": capacity,
+		"lobby":    req.Lobby,
+		"passcode": passcodeHash != "",
+		"schedule": scheduleInfo,
 	})
 }
 
@@ -57,19 +164,60 @@ func (s *ApiUseCases) HandleJoinRoom(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "room not specified", http.StatusBadRequest)
 		return
 	}
-	roomID := parts[3]
-
-	room, ok := s.roomRepository.GetRoom(roomID)
+	room, ok := s.resolveRoom(parts[3])
 	if !ok {
-		http.Error(w, fmt.Sprintf("room not found %s", roomID), http.StatusNotFound)
+		http.Error(w, fmt.Sprintf("room not found %s", parts[3]), http.StatusNotFound)
+		return
+	}
+	roomID := room.ID
+
+	if s.connections.Banned(roomID, claims.UserID) {
+		http.Error(w, "removed from the room by host", http.StatusForbidden)
+		return
+	}
+
+	var req JoinRoomRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	isHost := room.CreatorUserID == claims.UserID
+	if room.Locked && !isHost {
+		http.Error(w, "room is locked", http.StatusLocked)
 		return
 	}
 
-	if len(s.connections.WsClients) > MaxRoomUsers-1 {
+	// request admitted from the lobby passed the passcode when it was made, polling it does not ask again
+	admitted := false
+	if room.Lobby && !isHost && req.RequestID != "" {
+		if !s.passLobby(w, r, claims, roomID, room.CreatorUserID, req) {
+			return
+		}
+		admitted = true
+	}
+
+	if !isHost && !admitted && !s.checkPasscode(w, room, roomID, req.Passcode) {
+		return
+	}
+
+	capacity := room.Capacity
+	if capacity == 0 {
+		capacity = s.cfg.RoomConfig.DefaultCapacity
+	}
+
+	// every device takes its own slot
+	if s.connections.RoomSize(roomID) >= capacity {
 		http.Error(w, "room already full", http.StatusNotAcceptable)
 		return
 	}
 
+	if room.Lobby && !isHost && !admitted && !s.passLobby(w, r, claims, roomID, room.CreatorUserID, req) {
+		return
+	}
+
+	roomUsers := s.connections.RoomUserIDs(roomID)
+
 	// Обновляем jwt, чтобы он стал содержать RoomID
 	jwtStr, _, err := s.jwt.Issue(claims.UserID, claims.Username, roomID)
 	if err != nil {
@@ -80,12 +228,13 @@ func (s *ApiUseCases) HandleJoinRoom(w http.ResponseWriter, r *http.Request) {
 
 	// Обновляем метку времени комнаты, чтобы продлить время её жизни
 	s.roomRepository.RefreshRoom(roomID)
+	s.roomRepository.AddMember(roomID, claims.UserID)
 
 	// Send notification to room creator if he is absent but has push enabled
 	if s.pushService != nil && room.CreatorUserID != claims.UserID {
 		var notifyUsers []string
-		if len(s.connections.WsClients) > 0 {
-			for userID := range s.connections.WsClients {
+		if len(roomUsers) > 0 {
+			for _, userID := range roomUsers {
 				if userID != claims.UserID {
 					notifyUsers = append(notifyUsers, userID)
 				}
@@ -110,8 +259,11 @@ func (s *ApiUseCases) HandleJoinRoom(w http.ResponseWriter, r *http.Request) {
 
 	log.Printf("User %s (%s) joined room %s", claims.Username, claims.UserID, roomID)
 
-	writeJSON(w, map[string]string{
-		"jwt": jwtStr,
+	writeJSON(w, map[string]interface{}{
+		"room_id":      roomID,
+		"jwt":          jwtStr,
+		"capacity":     capacity,
+		"participants": s.connections.RoomSize(roomID),
 	})
 }
 
@@ -127,7 +279,7 @@ func (s *ApiUseCases) HandleInviteToRoom(w http.ResponseWriter, r *http.Request)
 		http.Error(w, "room not specified", http.StatusBadRequest)
 		return
 	}
-	roomID := parts[3]
+	idOrSlug := parts[3]
 
 	var req InviteRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -140,11 +292,12 @@ func (s *ApiUseCases) HandleInviteToRoom(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	_, ok := s.roomRepository.GetRoom(roomID)
+	room, ok := s.resolveRoom(idOrSlug)
 	if !ok {
 		http.Error(w, "room not found", http.StatusNotFound)
 		return
 	}
+	roomID := room.ID
 
 	invitedUser, err := s.userRepository.GetUserByUsername(entity.UsernameNormalize(req.InvitedUsername))
 	if err != nil {
@@ -179,15 +332,19 @@ func (s *ApiUseCases) HandleFetchRoom(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "room not specified", http.StatusBadRequest)
 		return
 	}
-	roomID := parts[3]
-
-	_, ok := s.roomRepository.GetRoom(roomID)
+	room, ok := s.resolveRoom(parts[3])
 	if !ok {
-		http.Error(w, fmt.Sprintf("room not found %s", roomID), http.StatusNotFound)
+		http.Error(w, fmt.Sprintf("room not found %s", parts[3]), http.StatusNotFound)
 		return
 	}
+	roomID := room.ID
 
-	writeJSON(w, map[string]string{
-		"exists": "true",
+	writeJSON(w, map[string]interface{}{
+		"exists":   "true",
+		"room_id":  roomID,
+		"slug":     room.Slug,
+		"passcode": room.PasscodeHash != "",
+		"locked":   room.Locked,
+		"lobby":    room.Lobby,
 	})
 }
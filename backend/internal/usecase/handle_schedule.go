@@ -0,0 +1,230 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+	"videocall/internal/domain/entity"
+	"videocall/internal/infrastructure/calendar"
+)
+
+// MaxInvitees limits invitee list of a scheduled meeting
+const MaxInvitees = 50
+
+// localTimeFormat is start of a meeting given without offset, read in the meeting time zone
+const localTimeFormat = "2006-01-02T15:04"
+
+// ScheduleRequest plans a meeting. Start is RFC 3339 or local time like 2026-01-02T15:04 in TimeZone
+type ScheduleRequest struct {
+	Start           string   `json:"start"`
+	TimeZone        string   `json:"time_zone,omitempty"`
+	DurationMinutes int      `json:"duration_minutes"`
+	Invitees        []string `json:"invitees,omitempty"`
+	// Recurrence turns the meeting into a series held in the same room
+	Recurrence *RecurrenceRequest `json:"recurrence,omitempty"`
+}
+
+// parseSchedule validates the request and resolves invitee usernames into user IDs
+func (s *ApiUseCases) parseSchedule(req *ScheduleRequest, hostUserID string) (*entity.Schedule, error) {
+	tz := req.TimeZone
+	if tz == "" {
+		tz = "UTC"
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q", tz)
+	}
+
+	startsAt, err := time.Parse(time.RFC3339, req.Start)
+	if err != nil {
+		startsAt, err = time.ParseInLocation(localTimeFormat, req.Start, loc)
+		if err != nil {
+			return nil, errors.New("start must be RFC 3339 time or " + localTimeFormat)
+		}
+	}
+
+	duration := time.Duration(req.DurationMinutes) * time.Minute
+	if duration <= 0 || duration > s.cfg.RoomConfig.MaxMeetingDuration {
+		return nil, fmt.Errorf("duration must be between 1 minute and %s", s.cfg.RoomConfig.MaxMeetingDuration)
+	}
+
+	if len(req.Invitees) > MaxInvitees {
+		return nil, fmt.Errorf("at most %d invitees allowed", MaxInvitees)
+	}
+
+	schedule := &entity.Schedule{
+		StartsAt: startsAt.UTC(),
+		Duration: duration,
+		TimeZone: loc.String(),
+	}
+
+	for _, username := range req.Invitees {
+		user, err := s.userRepository.GetUserByUsername(entity.UsernameNormalize(username))
+		if err != nil {
+			return nil, fmt.Errorf("user not found %s", username)
+		}
+		if user.ID != hostUserID && !slices.Contains(schedule.Invitees, user.ID) {
+			schedule.Invitees = append(schedule.Invitees, user.ID)
+		}
+	}
+
+	if req.Recurrence != nil {
+		if schedule.Recurrence, err = parseRecurrence(req.Recurrence, schedule); err != nil {
+			return nil, err
+		}
+	}
+
+	if !schedule.Endless() && !schedule.EndsAt().After(time.Now()) {
+		return nil, errors.New("meeting must end in the future")
+	}
+
+	return schedule, nil
+}
+
+// notifyInvitees sends push invite for the scheduled meeting to users who were not invited before
+func (s *ApiUseCases) notifyInvitees(hostUserID, hostUsername, roomID string, invitees, previous []string) {
+	if s.pushService == nil {
+		return
+	}
+
+	go func() {
+		for _, userID := range invitees {
+			if slices.Contains(previous, userID) {
+				continue
+			}
+			user, err := s.userRepository.GetUser(userID)
+			if err != nil || user.PushSubscription == nil {
+				continue
+			}
+			if err := s.pushService.NotifyRoomInvite(hostUserID, hostUsername, userID, roomID); err != nil {
+				log.Printf("Failed to send meeting invite notification: %v", err)
+			}
+		}
+	}()
+}
+
+func (s *ApiUseCases) scheduleInfo(schedule *entity.Schedule) map[string]interface{} {
+	invitees := make([]map[string]string, 0, len(schedule.Invitees))
+	for _, userID := range schedule.Invitees {
+		username := ""
+		if user, err := s.userRepository.GetUser(userID); err == nil {
+			username = user.Username
+		}
+		invitees = append(invitees, map[string]string{
+			"user_id":  userID,
+			"username": username,
+		})
+	}
+
+	// ends_at is null for endless series
+	var endsAt, nextStart interface{}
+	if !schedule.Endless() {
+		endsAt = schedule.EndsAt().Unix()
+	}
+	if next := schedule.Occurrences(time.Now(), maxTime, 1); len(next) > 0 {
+		nextStart = next[0].Unix()
+	}
+
+	return map[string]interface{}{
+		"starts_at":        schedule.StartsAt.Unix(),
+		"ends_at":          endsAt,
+		"next_start":       nextStart,
+		"local_start":      schedule.StartsAt.In(schedule.Location()).Format(localTimeFormat),
+		"time_zone":        schedule.TimeZone,
+		"duration_minutes": int(schedule.Duration / time.Minute),
+		"invitees":         invitees,
+		"recurrence":       recurrenceInfo(schedule),
+	}
+}
+
+// joinURL is absolute link to the room for use outside the app, vanity slug is preferred over room ID
+func (s *ApiUseCases) joinURL(r *http.Request, room *entity.Room) string {
+	base := s.cfg.PublicURL
+	if base == "" {
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+			scheme = proto
+		}
+		base = scheme + "://" + r.Host
+	}
+
+	link := room.ID
+	if room.Slug != "" {
+		link = room.Slug
+	}
+
+	return strings.TrimSuffix(base, "/") + "/join/" + link
+}
+
+// HandleRoomCalendar exports scheduled meeting of the room as iCalendar event
+func (s *ApiUseCases) HandleRoomCalendar(w http.ResponseWriter, r *http.Request) {
+	token, claims, err := s.validateAuthHeader(r)
+	if err != nil || !token.Valid {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) < 4 {
+		http.Error(w, "room not specified", http.StatusBadRequest)
+		return
+	}
+	room, ok := s.resolveRoom(parts[3])
+	if !ok {
+		http.Error(w, fmt.Sprintf("room not found %s", parts[3]), http.StatusNotFound)
+		return
+	}
+	roomID := room.ID
+
+	if !s.knowsRoom(room, claims.UserID) {
+		http.Error(w, "not a member of the room", http.StatusForbidden)
+		return
+	}
+
+	if room.Schedule == nil {
+		http.Error(w, "room has no scheduled meeting", http.StatusNotFound)
+		return
+	}
+
+	link := s.joinURL(r, room)
+	title := room.Title
+	if title == "" {
+		title = "Video call"
+	}
+
+	event := calendar.Event{
+		UID:         roomID + "@videocall",
+		Start:       room.Schedule.StartsAt,
+		End:         room.Schedule.StartsAt.Add(room.Schedule.Duration),
+		Summary:     title,
+		Description: "Join the call: " + link,
+		URL:         link,
+		Created:     room.CreatedAt,
+	}
+	if rec := room.Schedule.Recurrence; rec != nil {
+		event.Location = room.Schedule.Location()
+		event.Rule = &calendar.Rule{
+			Freq:     strings.ToUpper(rec.Freq),
+			Interval: rec.Interval,
+			ByDay:    rec.ByDay,
+			Count:    rec.Count,
+			Until:    rec.Until,
+		}
+		event.ExDates = rec.Exceptions
+	}
+
+	ics := calendar.Write(event)
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="meeting-%s.ics"`, roomID))
+	if _, err := w.Write(ics); err != nil {
+		log.Printf("failed to write calendar of room %s: %v", roomID, err)
+	}
+}
@@ -1,57 +1,179 @@
 package usecase
 
 import (
+	"errors"
 	"log"
 	"net/http"
+	"slices"
+	"videocall/internal/domain/repositories"
 	"videocall/internal/infrastructure/auth"
 	"videocall/internal/infrastructure/messaging"
 )
 
+var (
+	errInvalidTicket = errors.New("invalid or expired ticket")
+	errNoRoom        = errors.New("token is not bound to a room")
+	errRoomNotFound  = errors.New("room not found")
+	errBanned        = errors.New("removed from the room by host")
+	errRoomLocked    = errors.New("room is locked")
+)
+
 func (s *SignalingUseCases) SignalHandler(w http.ResponseWriter, r *http.Request) {
-	claims, ok := s.validateReq(w, r)
-	if !ok {
+	claims, err := s.authenticate(r)
+	if err != nil {
+		log.Printf("signaling auth failed: %v", err)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	client, err := messaging.NewClient(w, r, claims)
+	sessionID := r.URL.Query().Get("session")
+	var capacity int
+	if claims != nil {
+		capacity, err = s.admit(claims, sessionID)
+		if err != nil {
+			log.Printf("%s not admitted to room %q: %v", claims.Username, claims.RoomID, err)
+			http.Error(w, err.Error(), admissionStatus(err))
+			return
+		}
+	}
+
+	client, err := messaging.NewClient(w, r, claims, s.parseJWT, s.cfg.Signaling)
 	if err != nil {
 		log.Println("ws upgrade error:", err)
 		return
 	}
 
-	s.connections.AddClient(client, claims.RoomID)
+	// no credentials on upgrade: the first message has to be auth
+	if claims == nil {
+		claims, err = client.AwaitAuth()
+		if err != nil {
+			log.Printf("signaling in-band auth failed: %v", err)
+			client.CloseWithReason(messaging.CloseAuthRequired, "authentication required")
+			return
+		}
+
+		capacity, err = s.admit(claims, sessionID)
+		if err != nil {
+			log.Printf("%s not admitted to room %q: %v", claims.Username, claims.RoomID, err)
+			client.CloseWithReason(admissionCloseCode(err), err.Error())
+			return
+		}
+	}
+
+	hub, err := s.connections.AddClient(client, capacity, sessionID)
+	if err != nil {
+		log.Printf("%s rejected from room %s: %v", claims.Username, claims.RoomID, err)
+		client.CloseWithReason(messaging.CloseRoomFull, err.Error())
+		return
+	}
 
-	read := make(chan []byte, messaging.BufferSize)
-	done := make(chan struct{})
+	read := make(chan *messaging.Envelope, messaging.BufferSize)
+	// both Publisher and WritePump report completion, the second one must not block
+	done := make(chan struct{}, 2)
 
-	go s.connections.Publisher(s.ctx, client, read, done)
+	go s.connections.Publisher(s.ctx, client, hub, read, done)
 	go client.WritePump(s.ctx, done)
 	client.ReadPump(s.ctx, read)
 
-	<-done // WritePump
+	<-done // Publisher or WritePump
+
+	s.connections.RemoveClient(client)
+}
+
+// admit checks that the token is bound to an existing room which has a free slot.
+// Resuming session keeps its slot, so capacity is not checked for it. Only a session the user
+// still holds on this instance counts as resumed, made up session ID is a new join
+func (s *SignalingUseCases) admit(claims *auth.Claims, sessionID string) (int, error) {
+	if claims.RoomID == "" {
+		return 0, errNoRoom
+	}
+
+	room, ok := s.roomRepository.GetRoom(claims.RoomID)
+	if !ok {
+		return 0, errRoomNotFound
+	}
+
+	if s.connections.Banned(claims.RoomID, claims.UserID) {
+		return 0, errBanned
+	}
+
+	resuming := s.connections.OwnsSession(claims.RoomID, sessionID, claims.UserID)
+
+	// locked room lets in only the host and devices of users already in the call
+	if room.Locked && !resuming && room.CreatorUserID != claims.UserID &&
+		!slices.Contains(s.connections.RoomUserIDs(claims.RoomID), claims.UserID) {
+		return 0, errRoomLocked
+	}
+
+	capacity := room.Capacity
+	if capacity == 0 {
+		capacity = s.cfg.RoomConfig.DefaultCapacity
+	}
+
+	if !resuming && s.connections.RoomSize(claims.RoomID) >= capacity {
+		return 0, repositories.ErrRoomFull
+	}
+
+	return capacity, nil
+}
 
-	s.connections.RemoveClient(client, claims.RoomID)
+func admissionStatus(err error) int {
+	switch {
+	case errors.Is(err, errNoRoom), errors.Is(err, errBanned):
+		return http.StatusForbidden
+	case errors.Is(err, errRoomNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, repositories.ErrRoomFull):
+		return http.StatusNotAcceptable
+	case errors.Is(err, errRoomLocked):
+		return http.StatusLocked
+	default:
+		return http.StatusInternalServerError
+	}
 }
 
-func (s *SignalingUseCases) validateReq(w http.ResponseWriter, r *http.Request) (*auth.Claims, bool) {
-	jwtStr := r.URL.Query().Get("jwt")
-	if jwtStr == "" {
-		http.Error(w, "missing token", http.StatusUnauthorized)
-		return nil, false
+func admissionCloseCode(err error) int {
+	switch {
+	case errors.Is(err, repositories.ErrRoomFull):
+		return messaging.CloseRoomFull
+	case errors.Is(err, errBanned):
+		return messaging.CloseKicked
+	default:
+		return messaging.CloseNotAdmitted
+	}
+}
+
+// authenticate takes credentials from subprotocol, one-time ticket or, if allowed, jwt query parameter.
+// Nil claims without error means the client is expected to authenticate in-band
+func (s *SignalingUseCases) authenticate(r *http.Request) (*auth.Claims, error) {
+	if jwtStr := messaging.TokenFromSubprotocol(r); jwtStr != "" {
+		return s.parseJWT(jwtStr)
+	}
+
+	if ticket := r.URL.Query().Get("ticket"); ticket != "" {
+		claims, ok := s.tickets.Redeem(ticket)
+		if !ok {
+			return nil, errInvalidTicket
+		}
+		return claims, nil
+	}
+
+	if jwtStr := r.URL.Query().Get("jwt"); jwtStr != "" && s.cfg.Signaling.AllowQueryToken {
+		return s.parseJWT(jwtStr)
 	}
 
+	return nil, nil
+}
+
+func (s *SignalingUseCases) parseJWT(jwtStr string) (*auth.Claims, error) {
 	token, claims, err := s.jwt.GetToken(jwtStr)
 	if err != nil {
-		log.Printf("error getting jwt %s", err.Error())
-		http.Error(w, "error getting jwt", http.StatusUnauthorized)
-		return nil, false
+		return nil, err
 	}
 
 	if !token.Valid {
-		log.Printf("invalid jwt %s", jwtStr)
-		http.Error(w, "invalid jwt", http.StatusUnauthorized)
-		return nil, false
+		return nil, errors.New("invalid jwt")
 	}
 
-	return claims, true
+	return claims, nil
 }
@@ -0,0 +1,306 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"videocall/internal/domain/entity"
+	"videocall/internal/domain/repositories"
+	"videocall/internal/domain/repositories/mem"
+	"videocall/internal/infrastructure/auth"
+	"videocall/internal/infrastructure/bus"
+	"videocall/internal/infrastructure/config"
+	"videocall/internal/infrastructure/messaging"
+
+	"github.com/gorilla/websocket"
+)
+
+const testRoom = "room"
+
+const testWait = 5 * time.Second
+
+// testEnv is a single instance with in-memory storage, its signaling endpoint is served over real websockets
+type testEnv struct {
+	api    *ApiUseCases
+	signal *SignalingUseCases
+	rooms  *mem.RoomRepository
+	users  *mem.UserRepository
+	conns  *repositories.Connections
+	jwt    *auth.JWT
+	server *httptest.Server
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	cfg := &config.Config{
+		Signaling: config.Signaling{
+			PingInterval:     time.Second,
+			PongTimeout:      5 * time.Second,
+			WriteTimeout:     time.Second,
+			SendBuffer:       64,
+			MaxMessageSize:   65536,
+			RateLimitStrikes: 100,
+			ChatMaxLength:    2000,
+			SessionGrace:     time.Minute,
+		},
+		RoomConfig: config.RoomConfig{
+			DefaultCapacity:     4,
+			LobbyTimeout:        time.Minute,
+			LobbyWait:           100 * time.Millisecond,
+			PasscodeMaxAttempts: 3,
+			PasscodeWindow:      time.Minute,
+		},
+		Bus: config.Bus{
+			HeartbeatInterval: time.Second,
+			NodeTimeout:       3 * time.Second,
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	b, err := bus.New(ctx, cfg.Bus)
+	if err != nil {
+		t.Fatalf("bus: %v", err)
+	}
+
+	rooms := mem.New()
+	rooms.AddRoom(&entity.Room{ID: testRoom, CreatorUserID: "host"})
+	users := mem.NewUserRepository()
+	jwt := &auth.JWT{Secret: []byte("secret"), Ttl: time.Hour}
+	conns := repositories.NewConnections(ctx, cfg, b, rooms, mem.NewChatRepository(), mem.NewCallRepository())
+
+	e := &testEnv{
+		api: &ApiUseCases{
+			ctx:            ctx,
+			roomRepository: rooms,
+			userRepository: users,
+			cfg:            cfg,
+			jwt:            jwt,
+			connections:    conns,
+			passcodes:      newPasscodeThrottle(ctx, cfg.RoomConfig.PasscodeMaxAttempts, cfg.RoomConfig.PasscodeWindow),
+		},
+		signal: &SignalingUseCases{
+			ctx:            ctx,
+			roomRepository: rooms,
+			cfg:            cfg,
+			connections:    conns,
+			jwt:            jwt,
+		},
+		rooms: rooms,
+		users: users,
+		conns: conns,
+		jwt:   jwt,
+	}
+	e.server = httptest.NewServer(http.HandlerFunc(e.signal.SignalHandler))
+
+	t.Cleanup(func() {
+		cancel()
+		e.server.Close()
+		_ = b.Close()
+	})
+
+	return e
+}
+
+func (e *testEnv) token(t *testing.T, userID, roomID string) string {
+	t.Helper()
+
+	token, _, err := e.jwt.Issue(userID, userID, roomID)
+	if err != nil {
+		t.Fatalf("issue jwt: %v", err)
+	}
+
+	return token
+}
+
+// dial opens signaling connection with jwt offered as subprotocol, status is the HTTP status of a refused upgrade
+func (e *testEnv) dial(t *testing.T, userID, roomID, sessionID string) (*websocket.Conn, int) {
+	t.Helper()
+
+	url := "ws" + strings.TrimPrefix(e.server.URL, "http") + "/ws"
+	if sessionID != "" {
+		url += "?session=" + sessionID
+	}
+
+	dialer := websocket.Dialer{
+		Subprotocols:     []string{messaging.Subprotocol, messaging.SubprotocolTokenPrefix + e.token(t, userID, roomID)},
+		HandshakeTimeout: testWait,
+	}
+	conn, resp, err := dialer.Dial(url, nil)
+	if err != nil {
+		if resp == nil {
+			t.Fatalf("dial as %s: %v", userID, err)
+		}
+		return nil, resp.StatusCode
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	return conn, http.StatusSwitchingProtocols
+}
+
+// connect joins the room over signaling and returns the session ID assigned by the server
+func (e *testEnv) connect(t *testing.T, userID string) (*websocket.Conn, string) {
+	t.Helper()
+
+	conn, status := e.dial(t, userID, testRoom, "")
+	if conn == nil {
+		t.Fatalf("%s refused with %d", userID, status)
+	}
+
+	var session messaging.SessionPayload
+	if err := json.Unmarshal(expectFrame(t, conn, messaging.TypeSession).Payload, &session); err != nil {
+		t.Fatalf("malformed session payload: %v", err)
+	}
+
+	return conn, session.SessionID
+}
+
+// expectFrame skips other messages until one of msgType arrives
+func expectFrame(t *testing.T, conn *websocket.Conn, msgType string) *messaging.Envelope {
+	t.Helper()
+
+	_ = conn.SetReadDeadline(time.Now().Add(testWait))
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("waiting for %s: %v", msgType, err)
+		}
+
+		var env messaging.Envelope
+		if err := json.Unmarshal(data, &env); err != nil {
+			t.Fatalf("malformed message %s: %v", data, err)
+		}
+		if env.Type == msgType {
+			return &env
+		}
+	}
+}
+
+// waitSize polls room size, joins and leaves are applied after the handshake
+func (e *testEnv) waitSize(t *testing.T, size int) {
+	t.Helper()
+
+	deadline := time.Now().Add(testWait)
+	for e.conns.RoomSize(testRoom) != size {
+		if time.Now().After(deadline) {
+			t.Fatalf("room has %d participants, want %d", e.conns.RoomSize(testRoom), size)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// request calls REST handler as the user
+func (e *testEnv) request(t *testing.T, handler http.HandlerFunc, userID, path string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var reader io.Reader = http.NoBody
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("encode body: %v", err)
+		}
+		reader = strings.NewReader(string(data))
+	}
+
+	r := httptest.NewRequest(http.MethodPost, path, reader)
+	r.Header.Set("Authorization", "Bearer "+e.token(t, userID, ""))
+	w := httptest.NewRecorder()
+	handler(w, r)
+
+	return w
+}
+
+func TestAdmission(t *testing.T) {
+	e := newTestEnv(t)
+	e.rooms.AddRoom(&entity.Room{ID: "small", CreatorUserID: "host", Capacity: 1})
+
+	if _, status := e.dial(t, "alice", "", ""); status != http.StatusForbidden {
+		t.Fatalf("token without room: status %d, want %d", status, http.StatusForbidden)
+	}
+	if _, status := e.dial(t, "alice", "missing", ""); status != http.StatusNotFound {
+		t.Fatalf("unknown room: status %d, want %d", status, http.StatusNotFound)
+	}
+
+	if conn, _ := e.dial(t, "alice", "small", ""); conn == nil {
+		t.Fatal("first participant refused")
+	}
+	deadline := time.Now().Add(testWait)
+	for e.conns.RoomSize("small") != 1 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if _, status := e.dial(t, "bob", "small", ""); status != http.StatusNotAcceptable {
+		t.Fatalf("full room: status %d, want %d", status, http.StatusNotAcceptable)
+	}
+}
+
+func TestAdmissionResumeKeepsSlot(t *testing.T) {
+	e := newTestEnv(t)
+	room, _ := e.rooms.GetRoom(testRoom)
+	room.Capacity = 2
+	e.rooms.UpdateRoom(room)
+
+	alice, session := e.connect(t, "alice")
+	e.connect(t, "bob")
+	e.waitSize(t, 2)
+
+	// session of another user is not a resume, it must not bypass capacity
+	if _, status := e.dial(t, "mallory", testRoom, session); status != http.StatusNotAcceptable {
+		t.Fatalf("forged session: status %d, want %d", status, http.StatusNotAcceptable)
+	}
+
+	// alice drops and comes back within grace, her slot is still taken by her session
+	_ = alice.Close()
+	conn, status := e.dial(t, "alice", testRoom, session)
+	if conn == nil {
+		t.Fatalf("resume refused with %d", status)
+	}
+
+	var resumed messaging.SessionPayload
+	if err := json.Unmarshal(expectFrame(t, conn, messaging.TypeSession).Payload, &resumed); err != nil {
+		t.Fatalf("malformed session payload: %v", err)
+	}
+	if !resumed.Resumed || resumed.SessionID != session {
+		t.Fatalf("session %+v, want resumed %s", resumed, session)
+	}
+}
+
+func TestTokenRenewal(t *testing.T) {
+	e := newTestEnv(t)
+	conn, _ := e.connect(t, "alice")
+
+	send := func(token string) {
+		t.Helper()
+
+		frame := messaging.Frame(messaging.TypeAuth, messaging.AuthPayload{Token: token})
+		if err := conn.WriteMessage(websocket.TextMessage, frame); err != nil {
+			t.Fatalf("send auth: %v", err)
+		}
+	}
+
+	send(e.token(t, "alice", "other"))
+	var rejected messaging.ErrorPayload
+	if err := json.Unmarshal(expectFrame(t, conn, messaging.TypeError).Payload, &rejected); err != nil {
+		t.Fatalf("malformed error payload: %v", err)
+	}
+	if !strings.Contains(rejected.Message, messaging.ErrAuthMismatch.Error()) {
+		t.Fatalf("token for another room rejected with %q", rejected.Message)
+	}
+
+	send(e.token(t, "alice", testRoom))
+	var accepted messaging.AuthStatePayload
+	if err := json.Unmarshal(expectFrame(t, conn, messaging.TypeAuthAccepted).Payload, &accepted); err != nil {
+		t.Fatalf("malformed auth-accepted payload: %v", err)
+	}
+	if accepted.ExpiresAt <= time.Now().Unix() {
+		t.Fatalf("renewed session expires at %d", accepted.ExpiresAt)
+	}
+}
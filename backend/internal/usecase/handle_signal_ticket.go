@@ -0,0 +1,28 @@
+package usecase
+
+import (
+	"log"
+	"net/http"
+)
+
+// HandleIssueSignalTicket exchanges jwt from Authorization header for a one-time ticket
+// used as ?ticket= when connecting to signaling
+func (s *ApiUseCases) HandleIssueSignalTicket(w http.ResponseWriter, r *http.Request) {
+	token, claims, err := s.validateAuthHeader(r)
+	if err != nil || !token.Valid {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ticket, expiry, err := s.tickets.Issue(claims)
+	if err != nil {
+		log.Printf("Failed to issue signal ticket: %v", err)
+		http.Error(w, "failed to issue ticket", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"ticket":  ticket,
+		"expires": expiry.Unix(),
+	})
+}
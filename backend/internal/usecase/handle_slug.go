@@ -0,0 +1,218 @@
+package usecase
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"videocall/internal/domain/entity"
+	"videocall/internal/domain/repositories"
+)
+
+// MaxSlugsPerUser limits persistent rooms a user may hold
+const MaxSlugsPerUser = 10
+
+var (
+	slugPattern = regexp.MustCompile(`^[a-z0-9](?:[a-z0-9-]{1,38}[a-z0-9])$`)
+	// slugs must not be mistaken for generated room IDs
+	roomIDPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+)
+
+// reservedSlugs are app routes and words that would mislead participants
+var reservedSlugs = map[string]bool{
+	"about": true, "admin": true, "api": true, "app": true, "assets": true, "auth": true,
+	"calendar": true, "create": true, "help": true, "host": true, "join": true, "login": true,
+	"logout": true, "me": true, "new": true, "null": true, "official": true, "push": true,
+	"register": true, "room": true, "rooms": true, "root": true, "settings": true, "signal": true,
+	"static": true, "support": true, "system": true, "turn": true, "undefined": true,
+	"videocall": true, "ws": true, "www": true,
+}
+
+type SlugRequest struct {
+	Slug string `json:"slug"`
+}
+
+type TransferRoomRequest struct {
+	Username string `json:"username"`
+}
+
+// normalizeSlug lowercases slug and checks it: 3-40 latin letters, digits and inner dashes, not reserved
+func normalizeSlug(slug string) (string, error) {
+	slug = strings.ToLower(strings.TrimSpace(slug))
+
+	if !slugPattern.MatchString(slug) {
+		return "", errors.New("slug must be 3-40 latin letters, digits or dashes, not starting or ending with dash")
+	}
+
+	if reservedSlugs[slug] || roomIDPattern.MatchString(slug) {
+		return "", fmt.Errorf("slug %s is reserved", slug)
+	}
+
+	return slug, nil
+}
+
+// resolveRoom finds room by its ID or slug
+func (s *ApiUseCases) resolveRoom(idOrSlug string) (*entity.Room, bool) {
+	if room, ok := s.roomRepository.GetRoom(idOrSlug); ok {
+		return room, true
+	}
+
+	return s.roomRepository.GetRoomBySlug(strings.ToLower(idOrSlug))
+}
+
+// canClaimSlug checks that the user is registered and has not used up slugs. keepRoomID is the room whose slug is being replaced
+func (s *ApiUseCases) canClaimSlug(w http.ResponseWriter, userID, keepRoomID string) bool {
+	user, err := s.userRepository.GetUser(userID)
+	if err != nil || user.IsGuest {
+		http.Error(w, "only registered users can claim slugs", http.StatusForbidden)
+		return false
+	}
+
+	held := 0
+	for _, room := range s.roomRepository.ListRooms(userID) {
+		if room.CreatorUserID == userID && room.Slug != "" && room.ID != keepRoomID {
+			held++
+		}
+	}
+	if held >= MaxSlugsPerUser {
+		http.Error(w, fmt.Sprintf("at most %d slugs per user", MaxSlugsPerUser), http.StatusConflict)
+		return false
+	}
+
+	return true
+}
+
+// ownedRoom authorizes request of the room owner on /api/rooms/{id}/...
+func (s *ApiUseCases) ownedRoom(w http.ResponseWriter, r *http.Request) (*entity.Room, string, string, bool) {
+	token, claims, err := s.validateAuthHeader(r)
+	if err != nil || !token.Valid {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return nil, "", "", false
+	}
+
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) < 4 {
+		http.Error(w, "room not specified", http.StatusBadRequest)
+		return nil, "", "", false
+	}
+
+	room, ok := s.resolveRoom(parts[3])
+	if !ok {
+		http.Error(w, fmt.Sprintf("room not found %s", parts[3]), http.StatusNotFound)
+		return nil, "", "", false
+	}
+
+	if room.CreatorUserID != claims.UserID {
+		http.Error(w, "only the room owner can do this", http.StatusForbidden)
+		return nil, "", "", false
+	}
+
+	return room, claims.UserID, claims.Username, true
+}
+
+// HandleSetSlug claims slug for the room, replacing its previous one. The room is kept by cleanup while it has a slug
+func (s *ApiUseCases) HandleSetSlug(w http.ResponseWriter, r *http.Request) {
+	room, userID, username, ok := s.ownedRoom(w, r)
+	if !ok {
+		return
+	}
+
+	var req SlugRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	slug, err := normalizeSlug(req.Slug)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !s.canClaimSlug(w, userID, room.ID) {
+		return
+	}
+
+	if err := s.roomRepository.SetSlug(room.ID, slug); err != nil {
+		if errors.Is(err, repositories.ErrSlugTaken) {
+			http.Error(w, "slug is taken", http.StatusConflict)
+			return
+		}
+		log.Printf("failed to set slug of room %s: %v", room.ID, err)
+		http.Error(w, "cannot set slug", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("✅ %s claimed slug %s for room %s", username, slug, room.ID)
+	room.Slug = slug
+
+	writeJSON(w, map[string]interface{}{
+		"room_id":  room.ID,
+		"slug":     slug,
+		"join_url": s.joinURL(r, room),
+	})
+}
+
+// HandleReleaseSlug frees slug of the room, the room is cleaned up again after inactivity
+func (s *ApiUseCases) HandleReleaseSlug(w http.ResponseWriter, r *http.Request) {
+	room, _, username, ok := s.ownedRoom(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.roomRepository.SetSlug(room.ID, ""); err != nil {
+		log.Printf("failed to release slug of room %s: %v", room.ID, err)
+		http.Error(w, "cannot release slug", http.StatusInternalServerError)
+		return
+	}
+	// inactivity is counted from now on, otherwise an old room disappears right away
+	s.roomRepository.RefreshRoom(room.ID)
+
+	log.Printf("✅ %s released slug %s of room %s", username, room.Slug, room.ID)
+
+	writeJSON(w, map[string]interface{}{
+		"room_id": room.ID,
+		"slug":    "",
+	})
+}
+
+// HandleTransferRoom passes the room with its slug and host rights to another registered user
+func (s *ApiUseCases) HandleTransferRoom(w http.ResponseWriter, r *http.Request) {
+	room, userID, username, ok := s.ownedRoom(w, r)
+	if !ok {
+		return
+	}
+
+	var req TransferRoomRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Username == "" {
+		http.Error(w, "username required", http.StatusBadRequest)
+		return
+	}
+
+	newOwner, err := s.userRepository.GetUserByUsername(entity.UsernameNormalize(req.Username))
+	if err != nil {
+		http.Error(w, "user not found", http.StatusNotFound)
+		return
+	}
+
+	if newOwner.ID == userID {
+		http.Error(w, "room already belongs to the user", http.StatusBadRequest)
+		return
+	}
+
+	if room.Slug != "" && !s.canClaimSlug(w, newOwner.ID, "") {
+		return
+	}
+
+	s.roomRepository.SetOwner(room.ID, newOwner.ID)
+
+	log.Printf("✅ %s transferred room %s to %s", username, room.ID, newOwner.Username)
+
+	writeJSON(w, map[string]interface{}{
+		"room_id":         room.ID,
+		"creator_user_id": newOwner.ID,
+	})
+}
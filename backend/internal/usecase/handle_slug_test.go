@@ -0,0 +1,113 @@
+package usecase
+
+import (
+	"net/http"
+	"testing"
+	"videocall/internal/domain/entity"
+)
+
+func TestNormalizeSlug(t *testing.T) {
+	tests := []struct {
+		slug string
+		want string
+		ok   bool
+	}{
+		{"standup", "standup", true},
+		{"  Team-Sync ", "team-sync", true},
+		{"a1b", "a1b", true},
+		{"ab", "", false},
+		{"-team", "", false},
+		{"team-", "", false},
+		{"team_sync", "", false},
+		{"команда", "", false},
+		{"admin", "", false},
+		{"0123456789abcdef0123456789abcdef", "", false},
+	}
+
+	for _, tt := range tests {
+		got, err := normalizeSlug(tt.slug)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("normalizeSlug(%q) = %q, %v, want %q, ok %t", tt.slug, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func newSlugEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	e := newTestEnv(t)
+	for _, user := range []*entity.User{
+		{ID: "host", Username: "host"},
+		{ID: "alice", Username: "alice"},
+		{ID: "guest", Username: "guest", IsGuest: true},
+	} {
+		if err := e.users.CreateUser(user); err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+	}
+	e.rooms.AddRoom(&entity.Room{ID: "alice-room", CreatorUserID: "alice"})
+	e.rooms.AddRoom(&entity.Room{ID: "guest-room", CreatorUserID: "guest"})
+
+	return e
+}
+
+func (e *testEnv) setSlug(t *testing.T, userID, roomID, slug string) int {
+	t.Helper()
+
+	return e.request(t, e.api.HandleSetSlug, userID, "/api/rooms/"+roomID+"/slug", SlugRequest{Slug: slug}).Code
+}
+
+func TestSetSlug(t *testing.T) {
+	e := newSlugEnv(t)
+
+	if status := e.setSlug(t, "host", testRoom, "Standup"); status != http.StatusOK {
+		t.Fatalf("claim: status %d, want %d", status, http.StatusOK)
+	}
+
+	tests := []struct {
+		name   string
+		userID string
+		roomID string
+		slug   string
+		want   int
+	}{
+		{"taken", "alice", "alice-room", "standup", http.StatusConflict},
+		{"invalid", "alice", "alice-room", "a", http.StatusBadRequest},
+		{"reserved", "alice", "alice-room", "login", http.StatusBadRequest},
+		{"guest", "guest", "guest-room", "guest-room", http.StatusForbidden},
+		{"not owner", "alice", testRoom, "mine", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status := e.setSlug(t, tt.userID, tt.roomID, tt.slug); status != tt.want {
+				t.Fatalf("status %d, want %d", status, tt.want)
+			}
+		})
+	}
+}
+
+func TestJoinBySlug(t *testing.T) {
+	e := newSlugEnv(t)
+
+	if status := e.setSlug(t, "host", testRoom, "standup"); status != http.StatusOK {
+		t.Fatalf("claim: status %d, want %d", status, http.StatusOK)
+	}
+
+	status, joined := e.join(t, "alice", "standup", JoinRoomRequest{})
+	if status != http.StatusOK || joined.RoomID != testRoom {
+		t.Fatalf("join by slug: status %d, room %q, want %s", status, joined.RoomID, testRoom)
+	}
+
+	if w := e.request(t, e.api.HandleReleaseSlug, "host", "/api/rooms/"+testRoom+"/slug", nil); w.Code != http.StatusOK {
+		t.Fatalf("release: status %d, want %d", w.Code, http.StatusOK)
+	}
+	if status, _ := e.join(t, "alice", "standup", JoinRoomRequest{}); status != http.StatusNotFound {
+		t.Fatalf("join by released slug: status %d, want %d", status, http.StatusNotFound)
+	}
+
+	// released slug can be claimed by another user
+	if status := e.setSlug(t, "alice", "alice-room", "standup"); status != http.StatusOK {
+		t.Fatalf("claim released slug: status %d, want %d", status, http.StatusOK)
+	}
+}
@@ -16,39 +16,53 @@ type ApiUseCases struct {
 	ctx            context.Context
 	roomRepository repositories.RoomRepositoryInterface
 	userRepository repositories.UserRepositoryInterface
+	chatRepository repositories.ChatRepositoryInterface
+	callRepository repositories.CallRepositoryInterface
 	cfg            *config.Config
 	jwt            *auth.JWT
 	tokenService   *token.RefreshTokenService
+	tickets        *token.SignalTicketService
 	pushService    *push.Service
 	connections    *repositories.Connections
+	passcodes      *passcodeThrottle
 }
 
 type SignalingUseCases struct {
-	ctx         context.Context
-	connections *repositories.Connections
-	jwt         *auth.JWT
-	pushService *push.Service
+	ctx            context.Context
+	roomRepository repositories.RoomRepositoryInterface
+	cfg            *config.Config
+	connections    *repositories.Connections
+	jwt            *auth.JWT
+	tickets        *token.SignalTicketService
+	pushService    *push.Service
 }
 
-func NewApiUseCases(ctx context.Context, roomRepo repositories.RoomRepositoryInterface, userRepo repositories.UserRepositoryInterface, cfg *config.Config, jwt *auth.JWT, refreshTokenService *token.RefreshTokenService, pushService *push.Service, connections *repositories.Connections) *ApiUseCases {
+func NewApiUseCases(ctx context.Context, roomRepo repositories.RoomRepositoryInterface, userRepo repositories.UserRepositoryInterface, chatRepo repositories.ChatRepositoryInterface, callRepo repositories.CallRepositoryInterface, cfg *config.Config, jwt *auth.JWT, refreshTokenService *token.RefreshTokenService, tickets *token.SignalTicketService, pushService *push.Service, connections *repositories.Connections) *ApiUseCases {
 	return &ApiUseCases{
 		ctx:            ctx,
 		roomRepository: roomRepo,
 		userRepository: userRepo,
+		chatRepository: chatRepo,
+		callRepository: callRepo,
 		cfg:            cfg,
 		jwt:            jwt,
 		tokenService:   refreshTokenService,
+		tickets:        tickets,
 		pushService:    pushService,
 		connections:    connections,
+		passcodes:      newPasscodeThrottle(ctx, cfg.RoomConfig.PasscodeMaxAttempts, cfg.RoomConfig.PasscodeWindow),
 	}
 }
 
-func NewSignalingUseCases(ctx context.Context, connections *repositories.Connections, jwt *auth.JWT, pushService *push.Service) *SignalingUseCases {
+func NewSignalingUseCases(ctx context.Context, roomRepo repositories.RoomRepositoryInterface, cfg *config.Config, connections *repositories.Connections, jwt *auth.JWT, tickets *token.SignalTicketService, pushService *push.Service) *SignalingUseCases {
 	return &SignalingUseCases{
-		ctx:         ctx,
-		connections: connections,
-		jwt:         jwt,
-		pushService: pushService,
+		ctx:            ctx,
+		roomRepository: roomRepo,
+		cfg:            cfg,
+		connections:    connections,
+		jwt:            jwt,
+		tickets:        tickets,
+		pushService:    pushService,
 	}
 }
 
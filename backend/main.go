@@ -1,6 +1,9 @@
 package main
 
 import (
+	// meeting time zones are resolved in the alpine image that ships no zoneinfo
+	_ "time/tzdata"
+
 	"videocall/cmd"
 
 	_ "go.uber.org/automaxprocs"
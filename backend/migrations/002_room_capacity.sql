@@ -0,0 +1,3 @@
+-- Per-room participant limit for mesh calls
+
+ALTER TABLE rooms ADD COLUMN capacity INT NOT NULL DEFAULT 2 AFTER creator_user_id;
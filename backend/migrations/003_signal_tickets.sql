@@ -0,0 +1,9 @@
+-- Nonces of redeemed signaling tickets, so that a ticket is accepted once by any instance.
+-- Rows are deleted once the ticket expires
+
+CREATE TABLE IF NOT EXISTS redeemed_signal_tickets (
+    nonce VARCHAR(64) PRIMARY KEY,
+    expiry DATETIME(3) NOT NULL
+);
+
+CREATE INDEX idx_redeemed_signal_tickets_expiry ON redeemed_signal_tickets(expiry);
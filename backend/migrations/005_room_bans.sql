@@ -0,0 +1,9 @@
+-- Users kicked by the host, kept out of the room on every instance until the room is deleted
+
+CREATE TABLE IF NOT EXISTS room_bans (
+    room_id VARCHAR(255) NOT NULL,
+    user_id VARCHAR(255) NOT NULL,
+    banned_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (room_id, user_id),
+    FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE
+);
@@ -0,0 +1,3 @@
+-- Optional waiting room where the host admits join requests
+
+ALTER TABLE rooms ADD COLUMN lobby BOOLEAN NOT NULL DEFAULT FALSE AFTER capacity;
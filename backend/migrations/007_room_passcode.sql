@@ -0,0 +1,4 @@
+-- Optional join passcode (bcrypt hash) and room lock
+
+ALTER TABLE rooms ADD COLUMN passcode_hash VARCHAR(255) NOT NULL DEFAULT '' AFTER lobby;
+ALTER TABLE rooms ADD COLUMN locked BOOLEAN NOT NULL DEFAULT FALSE AFTER passcode_hash;
@@ -0,0 +1,13 @@
+-- Room titles and users who joined a room, used to list rooms of a user
+
+ALTER TABLE rooms ADD COLUMN title VARCHAR(255) NOT NULL DEFAULT '' AFTER id;
+
+CREATE TABLE IF NOT EXISTS room_members (
+    room_id VARCHAR(255) NOT NULL,
+    user_id VARCHAR(255) NOT NULL,
+    joined_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (room_id, user_id),
+    FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_room_members_user_id ON room_members(user_id);
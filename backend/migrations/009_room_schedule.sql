@@ -0,0 +1,16 @@
+-- Scheduled meetings: planned start, duration, time zone and invited users
+
+ALTER TABLE rooms ADD COLUMN starts_at DATETIME NULL AFTER locked;
+ALTER TABLE rooms ADD COLUMN duration_seconds INT NOT NULL DEFAULT 0 AFTER starts_at;
+ALTER TABLE rooms ADD COLUMN time_zone VARCHAR(64) NOT NULL DEFAULT '' AFTER duration_seconds;
+-- kept by cleanup until this time, NULL for ad hoc rooms
+ALTER TABLE rooms ADD COLUMN ends_at DATETIME NULL AFTER time_zone;
+
+CREATE TABLE IF NOT EXISTS room_invitees (
+    room_id VARCHAR(255) NOT NULL,
+    user_id VARCHAR(255) NOT NULL,
+    PRIMARY KEY (room_id, user_id),
+    FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_room_invitees_user_id ON room_invitees(user_id);
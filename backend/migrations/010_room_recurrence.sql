@@ -0,0 +1,4 @@
+-- Recurring meetings: rule and cancelled occurrences as JSON, NULL for a single meeting.
+-- Endless series have no ends_at and are not cleaned up
+
+ALTER TABLE rooms ADD COLUMN recurrence TEXT NULL AFTER time_zone;
@@ -0,0 +1,5 @@
+-- Vanity slugs of persistent rooms, NULL when room has none
+
+ALTER TABLE rooms ADD COLUMN slug VARCHAR(64) NULL AFTER title;
+
+CREATE UNIQUE INDEX idx_rooms_slug ON rooms(slug);
@@ -0,0 +1,28 @@
+-- Call history. Calls outlive their rooms, so there is no foreign key to rooms.
+-- DATETIME keeps start times from being bumped by ON UPDATE of the first TIMESTAMP column
+
+CREATE TABLE IF NOT EXISTS calls (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    room_id VARCHAR(255) NOT NULL,
+    -- set while the call is in progress, keeps one open call per room
+    active_room_id VARCHAR(255) NULL,
+    started_at DATETIME(3) NOT NULL,
+    ended_at DATETIME(3) NULL,
+    duration_seconds INT NOT NULL DEFAULT 0,
+    UNIQUE KEY uq_calls_active_room_id (active_room_id)
+);
+
+CREATE INDEX idx_calls_room_id ON calls(room_id, id);
+
+CREATE TABLE IF NOT EXISTS call_participants (
+    call_id BIGINT NOT NULL,
+    session_id VARCHAR(64) NOT NULL,
+    user_id VARCHAR(255) NOT NULL,
+    username VARCHAR(255) NOT NULL,
+    joined_at DATETIME(3) NOT NULL,
+    left_at DATETIME(3) NULL,
+    PRIMARY KEY (call_id, session_id),
+    FOREIGN KEY (call_id) REFERENCES calls(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_call_participants_user_id ON call_participants(user_id, call_id);
@@ -35,22 +35,59 @@ export default function JoinRoom() {
         if (jwt) {
             (async () => {
                 try {
-                    const res = await authFetch(`${BASE_PATH}/api/rooms/${room_id}/join`, {
+                    let res = await authFetch(`${BASE_PATH}/api/rooms/${room_id}/join`, {
                         method: "POST",
                     });
 
+                    // room with passcode: ask until accepted or cancelled
+                    while (res.status === 403 && (await res.clone().text()).includes("passcode")) {
+                        const passcode = window.prompt("Введите код комнаты");
+                        if (passcode === null) {
+                            break;
+                        }
+                        res = await authFetch(`${BASE_PATH}/api/rooms/${room_id}/join`, {
+                            method: "POST",
+                            headers: { "Content-Type": "application/json" },
+                            body: JSON.stringify({ passcode }),
+                        });
+                    }
+
+                    // lobby room: wait until host admits the request
+                    while (res.status === 202) {
+                        const pending = await res.json();
+                        setError("Ожидаем, пока организатор впустит вас...");
+                        res = await authFetch(`${BASE_PATH}/api/rooms/${room_id}/join`, {
+                            method: "POST",
+                            headers: { "Content-Type": "application/json" },
+                            body: JSON.stringify({ request_id: pending.request_id }),
+                        });
+                    }
+
                     if (res.ok) {
+                        setError(null);
                         const data = await res.json();
                         // Обновляем jwt поскольку в него добавлен теперь room_id
                         if (data.jwt) {
                             setAuth(data.jwt, userId, username);
                         }
 
-                        // Переходим на страницу звонка VideoRoom
-                        navigate(`/room/${room_id}`, {replace: true});
+                        // Переходим на страницу звонка VideoRoom, slug комнаты заменяем её ID
+                        navigate(`/room/${data.room_id || room_id}`, {replace: true});
                     } else if (res.status === 406) {
                         fadeError('Комната уже занята!');
                         setLoading(false);
+                    } else if (res.status === 403) {
+                        fadeError('Вход в комнату запрещён');
+                        setLoading(false);
+                    } else if (res.status === 423) {
+                        fadeError('Комната закрыта организатором');
+                        setLoading(false);
+                    } else if (res.status === 429) {
+                        fadeError('Слишком много неверных кодов, попробуйте позднее');
+                        setLoading(false);
+                    } else if (res.status === 408) {
+                        fadeError('Организатор не ответил, попробуйте ещё раз');
+                        setLoading(false);
                     } else if (res.status === 404) {
                         fadeError('Комната не найдена!');
                         setLoading(false);
@@ -1,6 +1,10 @@
 import {useEffect, useRef} from "react";
 import {useAuth} from "./contexts/AuthContext";
 
+function jwtClaims(token) {
+    return JSON.parse(atob(token.split(".")[1].replace(/-/g, "+").replace(/_/g, "/")));
+}
+
 export function Signaling({onRemoteUser, onRemoteStream, onPendingOffer, onStatsUpdate}) {
     const BASE_PATH = process.env.REACT_APP_BASE_PATH || "";
     const { username, userId, jwt, setAuth, isInitializing, refreshToken } = useAuth();
@@ -14,16 +18,50 @@ export function Signaling({onRemoteUser, onRemoteStream, onPendingOffer, onStats
     const isInitiator = useRef(false);
     const reconnectTimeoutRef = useRef(null);
     const isClosingRef = useRef(false);
+    const sessionIdRef = useRef(null);
+    // jwtRef is the latest token, renewal over the open socket replaces it without reconnecting
+    const jwtRef = useRef(jwt);
+    jwtRef.current = jwt;
+    // connection follows the room of the token, renewed token of the same room must not reconnect
+    const signalRoom = jwt ? jwtClaims(jwt).room : null;
+
+    // renewToken sends a fresh room-bound jwt over the open socket, so the call goes on without reconnect
+    const renewToken = async () => {
+        if (!refreshToken) {
+            console.warn("No refresh token, signaling will be closed on jwt expiry");
+            return;
+        }
+
+        try {
+            const claims = jwtClaims(jwtRef.current);
+            const res = await fetch(`${BASE_PATH}/api/auth/refresh`, {
+                method: "POST",
+                headers: { "Content-Type": "application/json" },
+                body: JSON.stringify({ token: refreshToken, room_id: claims.room }),
+            });
+            if (!res.ok) {
+                throw new Error(`refresh failed: ${res.status}`);
+            }
+
+            const data = await res.json();
+            // saved first, so that reconnect after renewal presents the fresh token
+            jwtRef.current = data.jwt;
+            setAuth(data.jwt, data.user_id, data.username);
+            sendSignal("auth", { token: data.jwt });
+        } catch (e) {
+            console.error("❌ Token renewal failed:", e);
+        }
+    };
 
-    const sendSignal = (obj) => {
+    const sendSignal = (type, payload, to) => {
         const ws = wsRef.current;
         if (!ws || ws.readyState !== WebSocket.OPEN) {
-            console.warn("⚠️ WS not open, can't send", obj.type);
+            console.warn("⚠️ WS not open, can't send", type);
             return false;
         }
         try {
-            ws.send(JSON.stringify(obj));
-            console.log("* ws =>", obj.type, obj.from ? `from ${obj.from}` : "");
+            ws.send(JSON.stringify({ v: 1, type, to, payload }));
+            console.log("* ws =>", type, to ? `to ${to}` : "");
             return true;
         } catch (err) {
             console.error("❌ Error sending signal:", err);
@@ -34,7 +72,7 @@ export function Signaling({onRemoteUser, onRemoteStream, onPendingOffer, onStats
     async function getIceServers() {
         try {
             const res = await fetch(`${BASE_PATH}/api/turn`, {
-                headers: { Authorization: `Bearer ${jwt}` },
+                headers: { Authorization: `Bearer ${jwtRef.current}` },
             });
 
             if (res.ok) {
@@ -87,7 +125,7 @@ export function Signaling({onRemoteUser, onRemoteStream, onPendingOffer, onStats
     }
 
     async function fetchTurnAndStart() {
-        if (!jwt) {
+        if (!jwtRef.current) {
             console.error("jwt not issued - please authorize first");
             return;
         }
@@ -193,7 +231,7 @@ export function Signaling({onRemoteUser, onRemoteStream, onPendingOffer, onStats
                 offer.sdp.includes('m=audio') ? '✅ audio' : '❌ no audio'
             );
             await pcRef.current.setLocalDescription(offer);
-            sendSignal({ type: "offer", offer, from: username });
+            sendSignal("offer", offer);
             console.log("✅ Initiated call. Sending offer to "+remoteUser.current);
         } catch (err) {
             console.error("❌ Error starting call:", err);
@@ -203,10 +241,10 @@ export function Signaling({onRemoteUser, onRemoteStream, onPendingOffer, onStats
 
     const handleOffer = async (msg) => {
         try {
-            console.log("📥 Received offer from", msg.from || "peer");
+            console.log("📥 Received offer from", msg.from_name || "peer");
 
             if (!remoteUser.current) {
-                remoteUser.current = msg.from;
+                remoteUser.current = msg.from_name;
                 if (onRemoteUser) onRemoteUser(remoteUser.current);
             }
 
@@ -222,7 +260,7 @@ export function Signaling({onRemoteUser, onRemoteStream, onPendingOffer, onStats
                 await onPendingOffer(pcRef.current)
             }
 
-            await pcRef.current.setRemoteDescription(new RTCSessionDescription(msg.offer));
+            await pcRef.current.setRemoteDescription(new RTCSessionDescription(msg.payload));
             isRemoteDescriptionSet.current = true;
             console.log("✅ Remote offer set (RemoteDescription)");
 
@@ -249,7 +287,7 @@ export function Signaling({onRemoteUser, onRemoteStream, onPendingOffer, onStats
                 answer.sdp.includes('m=audio') ? '✅ audio' : '❌ no audio'
             );
             await pcRef.current.setLocalDescription(answer);
-            sendSignal({ type: "answer", answer });
+            sendSignal("answer", answer);
             console.log("✅ Answer sent");
         } catch (err) {
             console.error("❌ Error handling offer:", err);
@@ -264,7 +302,7 @@ export function Signaling({onRemoteUser, onRemoteStream, onPendingOffer, onStats
             }
 
             console.log("📥 Received answer from peer");
-            await pcRef.current.setRemoteDescription(new RTCSessionDescription(msg.answer));
+            await pcRef.current.setRemoteDescription(new RTCSessionDescription(msg.payload));
             isRemoteDescriptionSet.current = true;
             console.log("✅ Remote description (answer) set");
 
@@ -283,18 +321,18 @@ export function Signaling({onRemoteUser, onRemoteStream, onPendingOffer, onStats
     };
 
     const handleCandidate = async (msg) => {
-        if (!msg.candidate) return;
+        if (!msg.payload) return;
 
         if (isRemoteDescriptionSet.current && pcRef.current) {
             try {
-                await pcRef.current.addIceCandidate(new RTCIceCandidate(msg.candidate));
-                console.log("✅ Added ICE candidate:", msg.candidate.candidate);
+                await pcRef.current.addIceCandidate(new RTCIceCandidate(msg.payload));
+                console.log("✅ Added ICE candidate:", msg.payload.candidate);
             } catch (err) {
                 console.error("❌ Error adding ICE candidate:", err);
             }
         } else {
             console.log("🕐 Candidate buffered (waiting for remote SDP)");
-            pendingCandidates.current.push(msg.candidate);
+            pendingCandidates.current.push(msg.payload);
         }
     };
 
@@ -319,7 +357,7 @@ export function Signaling({onRemoteUser, onRemoteStream, onPendingOffer, onStats
                 const protocol = e.candidate.protocol;
                 const address = e.candidate.address || 'unknown';
                 console.log(`🧊 ICE candidate generated: ${type} ${protocol} ${address}`);
-                sendSignal({ type: "candidate", candidate: e.candidate });
+                sendSignal("candidate", e.candidate);
             } else {
                 console.log("✅ All ICE candidates have been sent");
             }
@@ -370,7 +408,7 @@ export function Signaling({onRemoteUser, onRemoteStream, onPendingOffer, onStats
     };
 
     const endCall = () => {
-        sendSignal({ type: "endCall" });
+        sendSignal("endCall");
 
         isClosingRef.current = true;
         if (wsRef.current) {
@@ -417,10 +455,10 @@ export function Signaling({onRemoteUser, onRemoteStream, onPendingOffer, onStats
 
             case "hello":
                 if (!remoteUser.current) {
-                    remoteUser.current = msg.from;
+                    remoteUser.current = msg.from_name;
                     if (onRemoteUser) onRemoteUser(remoteUser.current);
 
-                    console.log(`📞 Got hello from ${msg.from}, I am ${username}`);
+                    console.log(`📞 Got hello from ${msg.from_name}, I am ${username}`);
                     console.log("📞 I will initiate the call (send offer)");
                     await fetchTurnAndStart();
                 }
@@ -430,6 +468,40 @@ export function Signaling({onRemoteUser, onRemoteStream, onPendingOffer, onStats
                 break;
             case "ping":
                 break;
+            case "session":
+                sessionIdRef.current = msg.payload?.session_id || null;
+                break;
+            case "mute-request": {
+                const kind = msg.payload?.kind || "audio";
+                console.log(`🔇 Host ${msg.from_name} asked to mute ${kind}`);
+                localStreamRef.current?.getTracks()
+                    .filter((track) => track.kind === kind)
+                    .forEach((track) => { track.enabled = false; });
+                break;
+            }
+            case "knock": {
+                const admit = window.confirm(`${msg.payload?.username} просится на звонок. Впустить?`);
+                sendSignal(admit ? "admit" : "deny", { request_id: msg.payload?.request_id });
+                break;
+            }
+            case "hands":
+                console.log("✋ Raised hands:", msg.payload?.hands);
+                break;
+            case "reaction":
+                console.log(`${msg.payload?.emoji} from ${msg.from_name}`);
+                break;
+            case "knock-resolved":
+                console.log("🚪 Lobby request resolved:", msg.payload);
+                break;
+            case "reauth-required":
+                await renewToken();
+                break;
+            case "auth-accepted":
+                console.log("✅ Signaling token renewed");
+                break;
+            case "error":
+                console.warn("⚠️ Signaling error:", msg.payload);
+                break;
             default:
                 console.warn("Unknown message:", msg);
         }
@@ -519,7 +591,7 @@ export function Signaling({onRemoteUser, onRemoteStream, onPendingOffer, onStats
             return;
         }
 
-        if (!jwt) {
+        if (!jwtRef.current) {
             console.error("JWT not issued!");
             return () => {};
         }
@@ -528,15 +600,19 @@ export function Signaling({onRemoteUser, onRemoteStream, onPendingOffer, onStats
 
         const connectWebSocket = () => {
             const origin = window.location.origin.replace(/^http/, "ws");
-            const url = `${origin}${BASE_PATH}/api/signal?jwt=${encodeURIComponent(jwt)}`;
+            let url = `${origin}${BASE_PATH}/api/signal`;
+            if (sessionIdRef.current) {
+                url += `?session=${encodeURIComponent(sessionIdRef.current)}`;
+            }
 
             console.log("Connecting to WebSocket:", url);
-            const ws = new WebSocket(url);
+            // jwt is passed as subprotocol so it does not end up in URLs and access logs
+            const ws = new WebSocket(url, ["videocall.v1", `jwt.${jwtRef.current}`]);
             wsRef.current = ws;
 
             ws.onopen = () => {
                 console.log("✅ WS connected");
-                sendSignal({ type: "hello", from: username });
+                sendSignal("hello");
             };
 
             ws.onmessage = async (ev) => {
@@ -558,8 +634,13 @@ export function Signaling({onRemoteUser, onRemoteStream, onPendingOffer, onStats
                 remoteUser.current = null;
                 onRemoteUser?.(null);
 
+                // kicked or meeting ended by host, rejoining is not possible
+                if (event.code === 4007 || event.code === 4008) {
+                    isClosingRef.current = true;
+                }
+
                 // Don't reconnect if we're intentionally closing
-                if (!isClosingRef.current && jwt) {
+                if (!isClosingRef.current && jwtRef.current) {
                     console.log("Attempting to reconnect in 3s...");
                     reconnectTimeoutRef.current = setTimeout(() => {
                         if (!isClosingRef.current) {
@@ -578,7 +659,7 @@ export function Signaling({onRemoteUser, onRemoteStream, onPendingOffer, onStats
 
         const pingInterval = setInterval(() => {
             if (wsRef.current?.readyState === WebSocket.OPEN) {
-                wsRef.current.send(JSON.stringify({ type: "ping" }));
+                wsRef.current.send(JSON.stringify({ v: 1, type: "ping" }));
             }
         }, 20000);
 
@@ -617,7 +698,7 @@ export function Signaling({onRemoteUser, onRemoteStream, onPendingOffer, onStats
             clearInterval(pingInterval);
             clearInterval(qualityInterval);
         };
-    }, [jwt, refreshToken, isInitializing, username]);
+    }, [signalRoom, refreshToken, isInitializing, username]);
 
     return {
         pcRef,